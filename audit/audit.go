@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionItemCreated        = "item.created"
+	ActionItemUpdated        = "item.updated"
+	ActionItemMoved          = "item.moved"
+	ActionItemPositioned     = "item.positioned"
+	ActionItemDeleted        = "item.deleted"
+	ActionItemUndeleted      = "item.undeleted"
+	ActionItemRestored       = "item.restored"
+	ActionItemArchived       = "item.archived"
+	ActionItemUnarchived     = "item.unarchived"
+	ActionItemSnoozed        = "item.snoozed"
+	ActionCommentAdded       = "comment.added"
+	ActionCommentUpdated     = "comment.updated"
+	ActionCommentDeleted     = "comment.deleted"
+	ActionChecklistAdded     = "checklist.added"
+	ActionChecklistToggled   = "checklist.toggled"
+	ActionChecklistReordered = "checklist.reordered"
+	ActionChecklistDeleted   = "checklist.deleted"
+	ActionAttachmentAdded    = "attachment.added"
+	ActionAttachmentDeleted  = "attachment.deleted"
+	ActionDependencyAdded    = "dependency.added"
+	ActionDependencyRemoved  = "dependency.removed"
+	ActionTimeAdded          = "time.added"
+	ActionTimeStarted        = "time.started"
+	ActionTimeStopped        = "time.stopped"
+	ActionTimeDeleted        = "time.deleted"
+	ActionLabelUpdated       = "label.updated"
+	ActionLabelMerged        = "label.merged"
+	ActionLabelDeleted       = "label.deleted"
+)
+
+// MaxLimit is the largest number of entries returned by one query
+const MaxLimit = 500
+
+// Change holds the value of one field before and after a mutation
+// Before is nil for created values and After is nil for deleted ones
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Entry defines the structure of one append-only audit log entry
+type Entry struct {
+	ID        int               `json:"id"`
+	ActorID   int               `json:"actorId"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	ItemID    int               `json:"itemId"`
+	Changes   map[string]Change `json:"changes"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Filter restricts the entries returned by an audit query, zero values do not restrict anything
+type Filter struct {
+	ActorID int
+	ItemID  int
+	Action  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+	// Before only keeps the entries older than the entry with this id, to page back through the log
+	Before int
+	// Latest returns the newest matching entries instead of the oldest ones, still from the oldest one
+	Latest bool
+}
+
+// Diff returns the fields which differ between the json representations of before and after
+// A nil before or after stands for a created or a deleted value
+func Diff(before, after interface{}) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+	for k, v := range b {
+		if !reflect.DeepEqual(v, a[k]) {
+			changes[k] = Change{Before: v, After: a[k]}
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = Change{After: v}
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the json fields of v
+func fields(v interface{}) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
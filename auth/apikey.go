@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// KeyPrefix starts every API key so it can be told apart from a session token
+const KeyPrefix = "tdl_"
+
+// GenerateKey returns a new random API key, its lookup prefix and the hash to store
+// The key has the form tdl_<prefix>_<secret>
+func GenerateKey() (key string, prefix string, hash string, err error) {
+	b := make([]byte, 4+32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:4])
+	key = KeyPrefix + prefix + "_" + hex.EncodeToString(b[4:])
+	return key, prefix, HashKey(key), nil
+}
+
+// HashKey returns the hex encoded sha256 of the key
+// The keys are random so a fast hash is enough to protect them at rest
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitKey returns the lookup prefix of the key
+func splitKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(key, KeyPrefix), "_", 2)
+	if len(parts) != 2 || len(parts[0]) != 8 {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// matchKey compares the key with the stored hash in constant time
+func matchKey(key string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashKey(key)), []byte(hash)) == 1
+}
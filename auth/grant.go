@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aflog/todolist/user"
+)
+
+// Grant describes how a request was authenticated and what it is allowed to do
+// Session tokens are granted all the scopes, API keys only the scopes they were created with
+// WorkspaceID is the workspace the credential is bound to, 0 if the request can select any workspace
+type Grant struct {
+	KeyID       int
+	Scopes      []string
+	WorkspaceID int
+}
+
+// Session reports whether the request was authenticated with a session token
+func (g Grant) Session() bool {
+	return g.KeyID == 0
+}
+
+// Allows reports whether the scope is granted
+func (g Grant) Allows(scope string) bool {
+	if g.Session() {
+		return true
+	}
+	for _, s := range g.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the grant of the request
+func NewContext(ctx context.Context, g Grant) context.Context {
+	return context.WithValue(ctx, contextKey{}, g)
+}
+
+// FromContext returns the grant carried by ctx, requests without one are treated as sessions
+func FromContext(ctx context.Context) Grant {
+	g, _ := ctx.Value(contextKey{}).(Grant)
+	return g
+}
+
+// RequireScopes rejects the requests whose grant does not allow the http method
+// Safe methods require items:read, all the others items:write
+func RequireScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := user.ScopeItemsWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			scope = user.ScopeItemsRead
+		}
+		if !FromContext(r.Context()).Allows(scope) {
+			http.Error(w, "The API key is missing the "+scope+" scope.", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aflog/todolist/user"
+)
+
+// lastUsedPrecision limits how often the last use of an API key is written
+const lastUsedPrecision = time.Minute
+
+// Storage returns the users and API keys the requests are authenticated with
+type Storage interface {
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*user.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error
+}
+
+// Middleware authenticates the requests before they reach the handlers
+type Middleware struct {
+	signer  *Signer
+	storage Storage
+}
+
+// NewMiddleware creates and sets up a new Middleware
+func NewMiddleware(s *Signer, storage Storage) *Middleware {
+	return &Middleware{signer: s, storage: storage}
+}
+
+// Authenticate requires a valid session token or API key and passes the authenticated user
+// and its grant in the request context
+// The credential is read from the "Authorization: Bearer" header, websocket clients which can not set
+// headers may send it in the access_token query parameter
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			unauthorized(w)
+			return
+		}
+
+		var userID int
+		var grant Grant
+		if strings.HasPrefix(token, KeyPrefix) {
+			k, err := m.apiKey(r.Context(), token)
+			if err != nil {
+				log.Println(err)
+				http.Error(w, "We could not authenticate the request.", http.StatusInternalServerError)
+				return
+			}
+			if k == nil {
+				unauthorized(w)
+				return
+			}
+			userID = k.UserID
+			grant = Grant{KeyID: k.ID, Scopes: k.Scopes, WorkspaceID: k.WorkspaceID}
+		} else {
+			id, workspaceID, err := m.signer.Verify(token)
+			if err != nil {
+				unauthorized(w)
+				return
+			}
+			userID = id
+			grant = Grant{WorkspaceID: workspaceID}
+		}
+
+		u, err := m.storage.GetUser(r.Context(), userID)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not authenticate the request.", http.StatusInternalServerError)
+			return
+		}
+		if u == nil {
+			unauthorized(w)
+			return
+		}
+		ctx := user.NewContext(r.Context(), u)
+		ctx = NewContext(ctx, grant)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiKey returns the stored key matching the provided one and nil if it is unknown or revoked
+func (m *Middleware) apiKey(ctx context.Context, key string) (*user.APIKey, error) {
+	prefix, ok := splitKey(key)
+	if !ok {
+		return nil, nil
+	}
+	k, err := m.storage.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil || k == nil {
+		return nil, err
+	}
+	if k.Revoked() || !matchKey(key, k.Hash) {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > lastUsedPrecision {
+		if err := m.storage.TouchAPIKey(ctx, k.ID, now); err != nil {
+			// the request is still authenticated, only the usage tracking failed
+			log.Println(err)
+		}
+	}
+	return k, nil
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return r.URL.Query().Get("access_token")
+	}
+	const prefix = "Bearer "
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(h[len(prefix):])
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="todolist"`)
+	http.Error(w, "Authentication required.", http.StatusUnauthorized)
+}
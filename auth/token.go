@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ErrInvalidToken is returned for tokens which are malformed, badly signed or expired
+var ErrInvalidToken = errors.New("auth: invalid token")
+
+// Signer issues and verifies the signed session tokens (HS256 JWTs)
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner creates a Signer with the secret used to sign the tokens and their time to live
+func NewSigner(secret string, ttl time.Duration) (*Signer, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("auth: secret must have at least 32 characters")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("auth: token time to live must be positive")
+	}
+	return &Signer{secret: []byte(secret), ttl: ttl}, nil
+}
+
+// claims of the session tokens
+// Workspace binds the token to one workspace, 0 lets the request select it
+type claims struct {
+	jwt.StandardClaims
+	Workspace int `json:"wsp,omitempty"`
+}
+
+// Sign returns a token identifying the user and its expiration time
+// A workspace id other than 0 restricts the token to that workspace
+func (s *Signer) Sign(userID int, workspaceID int) (string, time.Time, error) {
+	expires := time.Now().Add(s.ttl)
+	c := claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.Itoa(userID),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expires.Unix(),
+		},
+		Workspace: workspaceID,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(s.secret)
+	return token, expires, err
+}
+
+// Verify checks the token and returns the id of the user it identifies and of the workspace it is bound to
+func (s *Signer) Verify(token string) (int, int, error) {
+	c := claims{}
+	_, err := jwt.ParseWithClaims(token, &c, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, ErrInvalidToken
+		}
+		return s.secret, nil
+	})
+	if err != nil {
+		return 0, 0, ErrInvalidToken
+	}
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0, 0, ErrInvalidToken
+	}
+	return id, c.Workspace, nil
+}
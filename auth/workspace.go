@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aflog/todolist/workspace"
+)
+
+// WorkspaceHeader selects the workspace of a request
+const WorkspaceHeader = "X-Workspace"
+
+// WorkspaceStorage returns the workspaces the requests are resolved to
+// The role of the authenticated user is empty for workspaces it is not a member of
+type WorkspaceStorage interface {
+	GetWorkspace(ctx context.Context, id int) (*workspace.Workspace, error)
+	GetWorkspaceBySlug(ctx context.Context, slug string) (*workspace.Workspace, error)
+}
+
+// WorkspaceResolver selects the workspace of the authenticated requests
+type WorkspaceResolver struct {
+	storage    WorkspaceStorage
+	baseDomain string
+}
+
+// NewWorkspaceResolver creates and sets up a new WorkspaceResolver
+// With a base domain like todolist.example.com the requests to acme.todolist.example.com select the workspace acme
+func NewWorkspaceResolver(storage WorkspaceStorage, baseDomain string) *WorkspaceResolver {
+	return &WorkspaceResolver{storage: storage, baseDomain: strings.ToLower(baseDomain)}
+}
+
+// Resolve passes the workspace of the request in the request context, it must run after Authenticate
+// The workspace a credential is bound to takes precedence, then the X-Workspace header, then the subdomain,
+// requests which do not select any workspace use the default one
+// The authenticated user must be a member of the workspace
+func (wr *WorkspaceResolver) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slug := r.Header.Get(WorkspaceHeader)
+		if slug == "" {
+			slug = wr.subdomain(r.Host)
+		}
+
+		var ws *workspace.Workspace
+		var err error
+		if bound := FromContext(r.Context()).WorkspaceID; bound != 0 {
+			ws, err = wr.storage.GetWorkspace(r.Context(), bound)
+			if err == nil && ws != nil && slug != "" && slug != ws.Slug {
+				http.Error(w, "The credential is bound to another workspace.", http.StatusForbidden)
+				return
+			}
+		} else {
+			if slug == "" {
+				slug = workspace.DefaultSlug
+			}
+			ws, err = wr.storage.GetWorkspaceBySlug(r.Context(), slug)
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not resolve the workspace.", http.StatusInternalServerError)
+			return
+		}
+		// unknown workspaces and the ones of other teams are not told apart
+		if ws == nil || ws.Role == "" {
+			http.Error(w, "Workspace not found.", http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(workspace.NewContext(r.Context(), ws)))
+	})
+}
+
+// subdomain returns the workspace slug of the host, an empty string if the host is not a subdomain of the base domain
+func (wr *WorkspaceResolver) subdomain(host string) string {
+	if wr.baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, "."+wr.baseDomain) {
+		return ""
+	}
+	sub := strings.TrimSuffix(host, "."+wr.baseDomain)
+	if strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
@@ -0,0 +1,35 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrTooLarge is returned when the content is larger than the limit of the upload
+var ErrTooLarge = errors.New("blob: content too large")
+
+// ErrNotFound is returned when no content is stored under the key
+var ErrNotFound = errors.New("blob: not found")
+
+// Blob describes stored content
+// The key is derived from the content so the same content is stored once
+type Blob struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// Store keeps the content of the attachments outside of the database
+type Store interface {
+	// Put stores the content read from r up to limit bytes, the content type is sniffed from the content
+	Put(ctx context.Context, r io.Reader, limit int64) (Blob, error)
+	// Open returns the content stored under the key
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the content stored under the key unless it was stored again since before,
+	// removing missing content is not an error
+	Delete(ctx context.Context, key string, before time.Time) error
+	// Keys returns the keys of the content stored before the provided time
+	Keys(ctx context.Context, before time.Time) ([]string, error)
+}
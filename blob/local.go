@@ -0,0 +1,135 @@
+package blob
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// keyPattern matches the sha256 hex keys of the content
+var keyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// LocalStore stores the content in files of a local directory named by the sha256 of their content
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates the directory if it doesn't exist and returns a store keeping the content in it
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Put stores the content in a temporary file while hashing it and moves the file to its key
+// Content which is already stored is kept as it is
+func (s *LocalStore) Put(ctx context.Context, r io.Reader, limit int64) (Blob, error) {
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// the type is sniffed from the first 512 bytes as http.DetectContentType considers no more
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return Blob{}, err
+	}
+	b := Blob{ContentType: http.DetectContentType(head)}
+
+	h := sha256.New()
+	b.Size, err = io.Copy(io.MultiWriter(tmp, h), io.LimitReader(br, limit+1))
+	if err != nil {
+		return Blob{}, err
+	}
+	if b.Size > limit {
+		return Blob{}, ErrTooLarge
+	}
+	if err := tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+	b.Key = hex.EncodeToString(h.Sum(nil))
+
+	path := s.path(b.Key)
+	if _, err := os.Stat(path); err == nil {
+		// refresh the time of the content so it is not collected as unused before it is referenced
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); !os.IsNotExist(err) {
+			return b, err
+		}
+		// the content was collected meanwhile, it is stored again
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return Blob{}, err
+	}
+	return b, os.Rename(tmp.Name(), path)
+}
+
+// Open returns the file of the content stored under the key
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, error) {
+	if !keyPattern.MatchString(key) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the file of the content stored under the key unless it was stored again since before
+// The file is moved aside first, a Put refreshing it meanwhile is either seen here or stores the content again
+func (s *LocalStore) Delete(ctx context.Context, key string, before time.Time) error {
+	if !keyPattern.MatchString(key) {
+		return nil
+	}
+	path := s.path(key)
+	removed := filepath.Join(filepath.Dir(path), "delete-"+key)
+	err := os.Rename(path, removed)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(removed)
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().Before(before) {
+		// a Put which stored the content again meanwhile wrote the same content
+		return os.Rename(removed, path)
+	}
+	return os.Remove(removed)
+}
+
+// Keys returns the keys of the files last stored before the provided time
+func (s *LocalStore) Keys(ctx context.Context, before time.Time) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(s.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !keyPattern.MatchString(info.Name()) || !info.ModTime().Before(before) {
+			return nil
+		}
+		keys = append(keys, info.Name())
+		return ctx.Err()
+	})
+	return keys, err
+}
+
+// path spreads the files in subdirectories named by the first two characters of their key
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key)
+}
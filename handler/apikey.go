@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/auth"
+	"github.com/aflog/todolist/user"
+	"github.com/gorilla/mux"
+)
+
+// APIKeys returns the API keys of the authenticated user, the keys themselves are never returned
+func (h *Handler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	if !sessionOnly(w, r) {
+		return
+	}
+	keys, err := h.storage.GetAPIKeys(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the API keys.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// AddAPIKey creates a new API key for the authenticated user
+// The key is part of this response only, it can not be retrieved later
+func (h *Handler) AddAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !sessionOnly(w, r) {
+		return
+	}
+	var in user.APIKey
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	k := user.APIKey{Name: in.Name, Scopes: in.Scopes}
+	if err := k.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new API key.", http.StatusInternalServerError)
+		return
+	}
+	k.Prefix = prefix
+	k.Hash = hash
+	k.ID, err = h.storage.CreateAPIKey(r.Context(), k)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new API key.", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		user.APIKey
+		Key string `json:"key"`
+	}{k, key}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// RevokeAPIKey disables the API key identified by the request url
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !sessionOnly(w, r) {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+	found, err := h.storage.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not revoke the API key.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "API key not found.", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionOnly rejects the requests authenticated with an API key so a key can not create other keys
+// When the request is rejected the error response is already sent
+func sessionOnly(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.FromContext(r.Context()).Session() {
+		http.Error(w, "API keys can only be managed with a session token.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
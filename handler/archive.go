@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+)
+
+// Archive archives the items from the request body `{"ids":[1,2]}` and returns the number of archived items
+// Items which the user can not edit or which are already archived are skipped
+func (h *Handler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// Unarchive takes the items from the request body `{"ids":[1,2]}` out of the archive
+// and returns the number of unarchived items
+// Items which the user can not edit or which are not archived are skipped
+func (h *Handler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *Handler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	var in struct {
+		IDs []int `json:"ids"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(in.IDs) == 0 {
+		http.Error(w, "ids field is required and can not be empty", http.StatusBadRequest)
+		return
+	}
+
+	archive := h.storage.UnarchiveItems
+	if archived {
+		archive = h.storage.ArchiveItems
+	}
+	n, err := archive(r.Context(), in.IDs)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the items.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		Updated int `json:"updated"`
+	}{n}
+	writeJSON(w, http.StatusOK, response)
+}
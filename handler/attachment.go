@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aflog/todolist/blob"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/gorilla/mux"
+)
+
+// WithBlobStore keeps the content of the attachments in the store, uploads larger than maxSize bytes are rejected
+func WithBlobStore(s blob.Store, maxSize int64) Option {
+	return func(h *Handler) {
+		h.blobs = s
+		h.maxAttachmentSize = maxSize
+	}
+}
+
+// Attachments returns the attachments of the item identified by the request url from the oldest one
+func (h *Handler) Attachments(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	attachments, err := h.storage.GetAttachments(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the attachments.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// AddAttachment stores the file of the multipart form field "file" as new attachment of the item identified
+// by the request url and returns the attachment, the content type is sniffed from the content of the file
+// Attaching files to an item of a shared list requires the editor role
+func (h *Handler) AddAttachment(w http.ResponseWriter, r *http.Request) {
+	if !h.attachmentsEnabled(w) {
+		return
+	}
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	// the form is streamed to the store, the limit leaves room for the headers of the multipart parts
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxAttachmentSize+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "file field is required", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid multipart form", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		a := item.Attachment{ItemID: i.ID, Name: part.FileName()}
+		if err := a.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := h.blobs.Put(r.Context(), part, h.maxAttachmentSize)
+		if err == blob.ErrTooLarge {
+			http.Error(w, "The file is larger than "+strconv.FormatInt(h.maxAttachmentSize, 10)+" bytes.", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not store the file.", http.StatusInternalServerError)
+			return
+		}
+		a.Key, a.Size, a.ContentType = b.Key, b.Size, b.ContentType
+
+		a.ID, err = h.storage.CreateAttachment(r.Context(), a)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not create new attachment.", http.StatusInternalServerError)
+			return
+		}
+		created, err := h.storage.GetAttachment(r.Context(), i.ID, a.ID)
+		if err != nil || created == nil {
+			log.Println(err)
+			http.Error(w, "We could not retrieve the attachment.", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+		return
+	}
+}
+
+// DownloadAttachment streams the content of the attachment identified by the request url
+// Range requests are supported, only images and PDFs are displayed inline by the browsers
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	if !h.attachmentsEnabled(w) {
+		return
+	}
+	_, a, ok := h.requestedAttachment(w, r)
+	if !ok {
+		return
+	}
+	f, err := h.blobs.Open(r.Context(), a.Key)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the file.", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(a.ContentType, "image/") || a.ContentType == "application/pdf" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	http.ServeContent(w, r, a.Name, a.CreatedAt, f)
+}
+
+// DeleteAttachment removes the attachment identified by the request url
+// Removing attachments of an item of a shared list requires the editor role
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	i, a, ok := h.requestedAttachment(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	found, err := h.storage.DeleteAttachment(r.Context(), i.ID, a.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the attachment.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Attachment not found.", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestedAttachment returns the item and its attachment identified by the request url
+// When the attachment can not be returned the error response is already sent
+func (h *Handler) requestedAttachment(w http.ResponseWriter, r *http.Request) (*item.Item, *item.Attachment, bool) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["aid"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	a, err := h.storage.GetAttachment(r.Context(), i.ID, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the attachment.", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if a == nil {
+		http.Error(w, "Attachment not found.", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return i, a, true
+}
+
+func (h *Handler) attachmentsEnabled(w http.ResponseWriter) bool {
+	if h.blobs == nil {
+		http.Error(w, "Attachments are not enabled.", http.StatusNotFound)
+		return false
+	}
+	return true
+}
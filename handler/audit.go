@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/workspace"
+)
+
+// History returns the newest audit entries of the item identified by the request url from the oldest one
+// The before query parameter, the id of an entry, returns the entries older than it
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	before := 0
+	if v := r.URL.Query().Get("before"); v != "" {
+		var err error
+		if before, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid before parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	entries, err := h.storage.GetItemHistory(r.Context(), i.ID, before)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the item history.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// Audit returns the audit entries of the workspace filtered by the query parameters
+// actor, item, action, since, until, before and limit, only the admins of the workspace can read it
+func (h *Handler) Audit(w http.ResponseWriter, r *http.Request) {
+	ws, _ := workspace.FromContext(r.Context())
+	if ws == nil || ws.Role != workspace.RoleAdmin {
+		forbidden(w)
+		return
+	}
+
+	q := r.URL.Query()
+	f := audit.Filter{Action: q.Get("action")}
+	var err error
+	for name, v := range map[string]*int{"actor": &f.ActorID, "item": &f.ItemID, "limit": &f.Limit, "before": &f.Before} {
+		if q.Get(name) == "" {
+			continue
+		}
+		if *v, err = strconv.Atoi(q.Get(name)); err != nil {
+			http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	for name, v := range map[string]*time.Time{"since": &f.Since, "until": &f.Until} {
+		if q.Get(name) == "" {
+			continue
+		}
+		if *v, err = time.Parse(time.RFC3339, q.Get(name)); err != nil {
+			http.Error(w, "Invalid "+name+" parameter, expected RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.storage.GetAuditEntries(r.Context(), f)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the audit log.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/repository"
+	"github.com/gorilla/mux"
+)
+
+// AddChecklistEntry appends new entry to the checklist of the item identified by the request url and returns its id
+// Changing the checklist of an item of a shared list requires the editor role
+func (h *Handler) AddChecklistEntry(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var c item.ChecklistEntry
+	if err := readJSON(r, &c); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := c.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.AddChecklistEntry(r.Context(), i.ID, c)
+	if err == repository.ErrChecklistFull {
+		http.Error(w, "The checklist can not have more than 100 entries.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not add the checklist entry.", http.StatusInternalServerError)
+		return
+	}
+	h.publishItem(r, i.ID)
+
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// ToggleChecklistEntry checks or unchecks the checklist entry identified by the request url and returns it
+// Changing the checklist of an item of a shared list requires the editor role
+func (h *Handler) ToggleChecklistEntry(w http.ResponseWriter, r *http.Request) {
+	i, id, ok := h.requestedChecklistEntry(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.storage.ToggleChecklistEntry(r.Context(), i.ID, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the checklist entry.", http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.Error(w, "Checklist entry not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	writeJSON(w, http.StatusOK, c)
+}
+
+// ReorderChecklist orders the checklist of the item identified by the request url as the ids of the request body
+// `{"ids":[3,1,2]}` which must list every entry of the checklist exactly once
+// Changing the checklist of an item of a shared list requires the editor role
+func (h *Handler) ReorderChecklist(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var in struct {
+		IDs []int `json:"ids"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	err := h.storage.ReorderChecklist(r.Context(), i.ID, in.IDs)
+	if err == repository.ErrInvalidOrder {
+		http.Error(w, "ids must list every entry of the checklist exactly once", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not reorder the checklist.", http.StatusInternalServerError)
+		return
+	}
+	h.publishItem(r, i.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteChecklistEntry removes the checklist entry identified by the request url
+// Changing the checklist of an item of a shared list requires the editor role
+func (h *Handler) DeleteChecklistEntry(w http.ResponseWriter, r *http.Request) {
+	i, id, ok := h.requestedChecklistEntry(w, r)
+	if !ok {
+		return
+	}
+	found, err := h.storage.DeleteChecklistEntry(r.Context(), i.ID, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the checklist entry.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Checklist entry not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestedChecklistEntry returns the item and the id of its checklist entry identified by the request url
+// provided the user can change the checklist, otherwise the error response is already sent
+func (h *Handler) requestedChecklistEntry(w http.ResponseWriter, r *http.Request) (*item.Item, int, bool) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["cid"])
+	if err != nil {
+		http.Error(w, "Invalid checklist entry ID", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return nil, 0, false
+	}
+	return i, id, true
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+	"github.com/aflog/todolist/repository"
+	"github.com/gorilla/mux"
+)
+
+// Comments returns the comments of the item identified by the request url from the oldest one
+// The replies are nested under the comment they answer
+func (h *Handler) Comments(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	comments, err := h.storage.GetComments(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the comments.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, item.Thread(comments))
+}
+
+// CommentEdits returns the previous texts of the comment identified by the request url from the oldest one
+func (h *Handler) CommentEdits(w http.ResponseWriter, r *http.Request) {
+	_, c, ok := h.requestedComment(w, r)
+	if !ok {
+		return
+	}
+	edits, err := h.storage.GetCommentEdits(r.Context(), c.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the edits of the comment.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, edits)
+}
+
+// AddComment stores new comment of the current user on the item identified by the request url and returns its id
+// The comment replies to another comment of the item when its parentId is set
+// Commenting an item of a shared list requires the commenter role
+func (h *Handler) AddComment(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleCommenter) {
+		return
+	}
+
+	var c item.Comment
+	if err := readJSON(r, &c); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := c.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.AddComment(r.Context(), i.ID, c)
+	if err == repository.ErrNoParent {
+		http.Error(w, "Parent comment not found.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new comment.", http.StatusInternalServerError)
+		return
+	}
+	h.publishItem(r, i.ID)
+	h.notifyMentions(r, i.ID, id, "", c.Text)
+
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// UpdateComment replaces the text of the comment identified by the request url, the previous text is kept
+// Only the author can edit a comment, the comments without an author require the editor role on a shared list
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	i, c, ok := h.requestedComment(w, r)
+	if !ok {
+		return
+	}
+	switch c.AuthorID {
+	case currentUser(r).ID:
+		if !h.allowedOnList(w, r, i.ListID, list.RoleCommenter) {
+			return
+		}
+	case 0:
+		if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+			return
+		}
+	default:
+		forbidden(w)
+		return
+	}
+
+	var in item.Comment
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.ID = c.ID
+	found, err := h.storage.UpdateComment(r.Context(), i.ID, in)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the comment.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	h.notifyMentions(r, i.ID, c.ID, c.Text, in.Text)
+	c, err = h.storage.GetComment(r.Context(), i.ID, c.ID)
+	if err != nil || c == nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the comment.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// DeleteComment removes the comment identified by the request url together with its replies
+// Authors can delete their own comments, deleting the others on a shared list requires the editor role
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	i, c, ok := h.requestedComment(w, r)
+	if !ok {
+		return
+	}
+	required := list.RoleEditor
+	if c.AuthorID == currentUser(r).ID {
+		required = list.RoleCommenter
+	}
+	if !h.allowedOnList(w, r, i.ListID, required) {
+		return
+	}
+	found, err := h.storage.DeleteComment(r.Context(), i.ID, c.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the comment.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestedComment returns the item and its comment identified by the request url
+// When the comment can not be returned the error response is already sent
+func (h *Handler) requestedComment(w http.ResponseWriter, r *http.Request) (*item.Item, *item.Comment, bool) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["cid"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	c, err := h.storage.GetComment(r.Context(), i.ID, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the comment.", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if c == nil {
+		http.Error(w, "Comment not found.", http.StatusNotFound)
+		return nil, nil, false
+	}
+	return i, c, true
+}
+
+// publishItem broadcasts the current state of the item after a change of its comments
+func (h *Handler) publishItem(r *http.Request, id int) {
+	i, err := h.storage.GetItem(r.Context(), id)
+	if err != nil || i == nil {
+		log.Println(err)
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/repository"
+	"github.com/gorilla/mux"
+)
+
+// WithBlockersRequired rejects marking an item done while an item it is blocked by is not done
+func WithBlockersRequired() Option {
+	return func(h *Handler) {
+		h.blockersRequired = true
+	}
+}
+
+// Dependencies returns the items the item identified by the request url is blocked by and the items it blocks
+func (h *Handler) Dependencies(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	d, err := h.storage.GetDependencies(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the dependencies.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// AddDependency makes the item identified by the request url blocked by the item of the request body `{"blockedBy":3}`
+// Returns StatusConflict when the blocker already depends on the item
+// Changing the dependencies of an item of a shared list requires the editor role
+func (h *Handler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var in struct {
+		BlockedBy int `json:"blockedBy"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if in.BlockedBy == 0 {
+		http.Error(w, "The blockedBy item is required.", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.storage.AddDependency(r.Context(), i.ID, in.BlockedBy)
+	if err == repository.ErrCycle {
+		http.Error(w, "The dependency would create a cycle.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not add the dependency.", http.StatusInternalServerError)
+		return
+	}
+	// the item was found above so it is the blocker which doesn't exist
+	if !found {
+		http.Error(w, "The blockedBy item does not exist.", http.StatusBadRequest)
+		return
+	}
+	d, err := h.storage.GetDependencies(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the dependencies.", http.StatusInternalServerError)
+		return
+	}
+	h.publishItem(r, i.ID)
+	writeJSON(w, http.StatusCreated, d)
+}
+
+// RemoveDependency makes the item identified by the request url no longer blocked by the blocker of the request url
+// Changing the dependencies of an item of a shared list requires the editor role
+func (h *Handler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	blockerID, err := strconv.Atoi(mux.Vars(r)["blockerId"])
+	if err != nil {
+		http.Error(w, "Invalid blocker ID", http.StatusBadRequest)
+		return
+	}
+	found, err := h.storage.RemoveDependency(r.Context(), i.ID, blockerID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not remove the dependency.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Dependency not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,43 +7,86 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/aflog/todolist/auth"
+	"github.com/aflog/todolist/blob"
 	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/label"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/markdown"
+	"github.com/aflog/todolist/realtime"
 	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/user"
+	"github.com/aflog/todolist/workspace"
 	"github.com/gorilla/mux"
 )
 
 //Handler holds set up for a to do list items hadler
 type Handler struct {
-	storage repository.Repository
+	storage           repository.Repository
+	hub               *realtime.Hub
+	signer            *auth.Signer
+	blobs             blob.Store
+	maxAttachmentSize int64
+	blockersRequired  bool
+}
+
+// Option sets an optional dependency of the Handler
+type Option func(*Handler)
+
+// WithHub broadcasts the item mutations to the clients subscribed to the hub
+func WithHub(hub *realtime.Hub) Option {
+	return func(h *Handler) {
+		h.hub = hub
+	}
+}
+
+// WithSigner issues session tokens to the users logging in
+func WithSigner(s *auth.Signer) Option {
+	return func(h *Handler) {
+		h.signer = s
+	}
 }
 
 //New creates and sets up a new items handler
-func New(s repository.Repository) (*Handler, error) {
+func New(s repository.Repository, opts ...Option) (*Handler, error) {
 	if s == nil {
 		return nil, errors.New("storage can not be nil")
 	}
-	return &Handler{storage: s}, nil
+	h := &Handler{storage: s}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
 }
 
 // List searches for all items and returns them through the http response
+// The archived items are only listed with the includeArchived=true query parameter and the items which have not
+// started yet or are snoozed with includeDeferred=true, see itemQuery for the filters
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	items, err := h.storage.GetItems(r.Context())
+	q, ok := itemQuery(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.storage.GetItems(r.Context(), q)
 	if err != nil {
 		log.Println(err)
 		http.Error(w, "We could not retrieve the to do list items.", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(items)
+	for k := range items {
+		if !renderItem(w, r, &items[k]) {
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
 }
 
 // Select searches for an item based on an id from the request url and returns it in the http response
 // Returns StatusNotFound if requested item id does not exist
 func (h *Handler) Select(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid product ID", http.StatusBadRequest)
 		return
@@ -59,37 +102,36 @@ func (h *Handler) Select(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Item not found.", http.StatusNotFound)
 		return
 	}
+	if !renderItem(w, r, items) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(items)
+	writeJSON(w, http.StatusOK, items)
 }
 
 // Add stores new item and returns its id in the http response
+// Adding an item to a shared list requires the editor role
 func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
 	// get item from request body
-	b, err := ioutil.ReadAll(r.Body)
-	defer r.Body.Close()
-	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
-	}
-
 	var inItem item.Item
-	err = json.Unmarshal(b, &inItem)
-	if err != nil {
+	if err := readJSON(r, &inItem); err != nil {
 		log.Println(err.Error())
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
 	// validate item data
-	err = inItem.Validate()
+	err := inItem.Validate()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !h.allowedOnList(w, r, inItem.ListID, list.RoleEditor) {
+		return
+	}
+	if !h.itemState(w, r, inItem.ListID, "", &inItem) {
+		return
+	}
 
 	// create item
 	id, err := h.storage.CreateItem(r.Context(), inItem)
@@ -98,11 +140,238 @@ func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "We could not create new item.", http.StatusInternalServerError)
 		return
 	}
+	inItem.ID = id
+	inItem.OwnerID = currentUser(r).ID
+	h.publish(r, realtime.TypeItemCreated, inItem)
+	h.notifyMentions(r, id, 0, "", inItem.Description)
+
 	// send response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	response := struct {
 		ID int `json:"id"`
 	}{id}
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// Update replaces the item identified by the request url with the one from the request body
+// The list and the comments of the item are kept, updating an item of a shared list requires the editor role
+// A new state must be allowed by the workflow of the list, a new status alone puts the item in the first state
+// matching it
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var inItem item.Item
+	if err := readJSON(r, &inItem); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := inItem.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.itemState(w, r, i.ListID, i.State, &inItem) {
+		return
+	}
+
+	if h.blockersRequired && inItem.Status && !i.Status && i.Blocked {
+		http.Error(w, "The item is blocked by items which are not done.", http.StatusConflict)
+		return
+	}
+
+	previous := i.Description
+	inItem.ID = i.ID
+	if !h.updateItem(w, r, inItem) {
+		return
+	}
+	i, ok = h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+	h.notifyMentions(r, i.ID, 0, previous, i.Description)
+	if !renderItem(w, r, i) {
+		return
+	}
+	writeJSON(w, http.StatusOK, i)
+}
+
+// Delete removes the item identified by the request url, deleting an item of a shared list requires the editor role
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	found, err := h.storage.DeleteItem(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the item.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return
+	}
+	h.publish(r, realtime.TypeItemDeleted, *i)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// itemQuery returns the listing options from the query parameters of the request
+// The q parameter holds search terms separated by spaces, label:client/acme/* filters by label
+// sort=position orders the items in their manual order
+// When the parameters are invalid the error response is already sent
+func itemQuery(w http.ResponseWriter, r *http.Request) (item.Query, bool) {
+	q := item.Query{}
+	if v := r.URL.Query().Get("includeArchived"); v != "" {
+		var err error
+		if q.IncludeArchived, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid includeArchived parameter", http.StatusBadRequest)
+			return q, false
+		}
+	}
+	if v := r.URL.Query().Get("includeDeferred"); v != "" {
+		var err error
+		if q.IncludeDeferred, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid includeDeferred parameter", http.StatusBadRequest)
+			return q, false
+		}
+	}
+	switch q.Sort = r.URL.Query().Get("sort"); q.Sort {
+	case "", item.SortPosition:
+	default:
+		http.Error(w, "Invalid sort parameter, expected position", http.StatusBadRequest)
+		return q, false
+	}
+	for _, term := range strings.Fields(r.URL.Query().Get("q")) {
+		if !strings.HasPrefix(term, "label:") {
+			http.Error(w, "Invalid search term "+term+", expected label:name", http.StatusBadRequest)
+			return q, false
+		}
+		name := strings.TrimPrefix(term, "label:")
+		wildcard := strings.HasSuffix(name, item.Wildcard)
+		name, err := label.Normalize(strings.TrimSuffix(name, item.Wildcard))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return q, false
+		}
+		if wildcard {
+			name += item.Wildcard
+		}
+		q.Labels = append(q.Labels, name)
+	}
+	return q, true
+}
+
+// renderItem sets the HTML of the Markdown description of the item when the request asks for it with render=html
+// When the parameter is invalid the error response is already sent
+func renderItem(w http.ResponseWriter, r *http.Request, i *item.Item) bool {
+	switch r.URL.Query().Get("render") {
+	case "":
+		return true
+	case "html":
+	default:
+		http.Error(w, "Invalid render parameter, expected html", http.StatusBadRequest)
+		return false
+	}
+	html, err := markdown.Render(i.Description)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not render the description.", http.StatusInternalServerError)
+		return false
+	}
+	i.DescriptionHTML = html
+	return true
+}
+
+// requestedItem returns the item identified by the request url
+// When the item can not be returned the error response is already sent
+func (h *Handler) requestedItem(w http.ResponseWriter, r *http.Request) (*item.Item, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return nil, false
+	}
+	i, err := h.storage.GetItem(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested item.", http.StatusInternalServerError)
+		return nil, false
+	}
+	if i == nil {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return nil, false
+	}
+	return i, true
+}
+
+// Subscribe upgrades the request to a websocket receiving the mutations of the items without a list
+// and the presence of other clients
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	h.subscribe(w, r, 0)
+}
+
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, listID int) {
+	if h.hub == nil {
+		http.Error(w, "Real-time updates are not enabled.", http.StatusNotFound)
+		return
+	}
+	// the username identifies the client in presence and typing messages
+	u, ok := user.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required.", http.StatusUnauthorized)
+		return
+	}
+	// the upgrader already responded to the client in case of an error
+	if err := h.hub.Serve(w, r, topic(r, listID), listID, u.Username); err != nil {
+		log.Println(err)
+	}
+}
+
+// publish broadcasts the item mutation to the clients subscribed to the list of the item
+func (h *Handler) publish(r *http.Request, messageType string, i item.Item) {
+	m := realtime.Message{Type: messageType, ListID: i.ListID, ItemID: i.ID}
+	h.hub.Publish(topic(r, i.ListID), m, i)
+}
+
+// topic returns the hub topic of the list, items without a list are only visible to the requesting user
+func topic(r *http.Request, listID int) string {
+	if listID != 0 {
+		return realtime.ListTopic(listID)
+	}
+	ws, _ := workspace.FromContext(r.Context())
+	return realtime.UserTopic(ws.ID, currentUser(r).ID)
+}
+
+// currentUser returns the authenticated user, the routes of the handler are only reachable when authenticated
+func currentUser(r *http.Request) *user.User {
+	u, ok := user.FromContext(r.Context())
+	if !ok {
+		return &user.User{}
+	}
+	return u
+}
+
+// readJSON decodes the request body into v
+func readJSON(r *http.Request, v interface{}) error {
+	b, err := ioutil.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// writeJSON sends v as json with the provided http status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
 }
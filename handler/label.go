@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aflog/todolist/label"
+	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/workspace"
+	"github.com/gorilla/mux"
+)
+
+// Labels returns all the labels of the workspace with the number of tagged items
+func (h *Handler) Labels(w http.ResponseWriter, r *http.Request) {
+	labels, err := h.storage.GetLabels(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the labels.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, labels)
+}
+
+// LabelTree returns the hierarchy of the labels like client/acme/billing with the number of open items
+// of each level
+func (h *Handler) LabelTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.storage.GetLabelTree(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the labels.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// SuggestLabels returns the labels starting with the prefix query parameter, the labels used often
+// and recently come first, the limit query parameter sets the number of labels, 10 by default
+func (h *Handler) SuggestLabels(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > label.MaxSuggestions {
+			http.Error(w, "Invalid limit parameter, expected 1 to 50", http.StatusBadRequest)
+			return
+		}
+	}
+	labels, err := h.storage.SuggestLabels(r.Context(), strings.TrimSpace(r.URL.Query().Get("prefix")), limit)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the labels.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, labels)
+}
+
+// SelectLabel returns the label identified by the request url
+func (h *Handler) SelectLabel(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedLabel(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// AddLabel stores new label and returns its id in the http response
+// Returns StatusConflict if the workspace already has a label with the same name
+func (h *Handler) AddLabel(w http.ResponseWriter, r *http.Request) {
+	var in label.Label
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.CreateLabel(r.Context(), in)
+	if err == repository.ErrConflict {
+		http.Error(w, "A label with this name already exists.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new label.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// UpdateLabel replaces the name, color and description of the label identified by the request url
+// A new name applies to every tagged item, renaming to the name of another label returns StatusConflict,
+// such labels are merged instead
+func (h *Handler) UpdateLabel(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedLabel(w, r)
+	if !ok {
+		return
+	}
+	var in label.Label
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.ID = l.ID
+	err := h.storage.UpdateLabel(r.Context(), in)
+	if err == repository.ErrConflict {
+		http.Error(w, "A label with this name already exists, merge the labels instead.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the label.", http.StatusInternalServerError)
+		return
+	}
+	l, ok = h.requestedLabel(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// DeleteLabel removes the label identified by the request url from the workspace and from every tagged item
+// The labels are shared by the workspace, only its admins can delete them
+func (h *Handler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	ws, _ := workspace.FromContext(r.Context())
+	if ws == nil || ws.Role != workspace.RoleAdmin {
+		forbidden(w)
+		return
+	}
+	l, ok := h.requestedLabel(w, r)
+	if !ok {
+		return
+	}
+	if err := h.storage.DeleteLabel(r.Context(), l.ID); err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the label.", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MergeLabel replaces the label identified by the request url with the label from the request body
+// `{"into":2}` on every tagged item and removes it, only the admins of the workspace can merge labels
+func (h *Handler) MergeLabel(w http.ResponseWriter, r *http.Request) {
+	ws, _ := workspace.FromContext(r.Context())
+	if ws == nil || ws.Role != workspace.RoleAdmin {
+		forbidden(w)
+		return
+	}
+	l, ok := h.requestedLabel(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		Into int `json:"into"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Into == 0 || in.Into == l.ID {
+		http.Error(w, "into field must be the id of another label", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.storage.MergeLabels(r.Context(), l.ID, in.Into)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not merge the labels.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Label not found.", http.StatusNotFound)
+		return
+	}
+	target, err := h.storage.GetLabel(r.Context(), in.Into)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested label.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, target)
+}
+
+// requestedLabel returns the label identified by the request url
+// When the label can not be returned the error response is already sent
+func (h *Handler) requestedLabel(w http.ResponseWriter, r *http.Request) (*label.Label, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid label ID", http.StatusBadRequest)
+		return nil, false
+	}
+	l, err := h.storage.GetLabel(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested label.", http.StatusInternalServerError)
+		return nil, false
+	}
+	if l == nil {
+		http.Error(w, "Label not found.", http.StatusNotFound)
+		return nil, false
+	}
+	return l, true
+}
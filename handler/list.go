@@ -0,0 +1,294 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+	"github.com/gorilla/mux"
+)
+
+// Lists searches for all lists and returns them through the http response
+func (h *Handler) Lists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.storage.GetLists(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the lists.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, lists)
+}
+
+// SelectList searches for a list based on an id from the request url and returns it in the http response
+// Returns StatusNotFound if requested list id does not exist
+func (h *Handler) SelectList(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// AddList stores new list and returns its id in the http response
+func (h *Handler) AddList(w http.ResponseWriter, r *http.Request) {
+	var inList list.List
+	if err := readJSON(r, &inList); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := inList.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.CreateList(r.Context(), inList)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new list.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// UpdateList replaces the list identified by the request url with the one from the request body
+// Only the owner of the list can update it
+func (h *Handler) UpdateList(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+
+	var inList list.List
+	if err := readJSON(r, &inList); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := inList.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	inList.ID = l.ID
+	inList.OwnerID = l.OwnerID
+	inList.Role = l.Role
+	if err := h.storage.UpdateList(r.Context(), inList); err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the list.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, inList)
+}
+
+// DeleteList removes the list identified by the request url, its items are kept without a list
+// Only the owner of the list can delete it
+func (h *Handler) DeleteList(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+	if err := h.storage.DeleteList(r.Context(), l.ID); err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the list.", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListItems searches for all items of the list identified by the request url
+// The archived items are only listed with the includeArchived=true query parameter
+func (h *Handler) ListItems(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	q, ok := itemQuery(w, r)
+	if !ok {
+		return
+	}
+	items, err := h.storage.GetListItems(r.Context(), l.ID, q)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the to do list items.", http.StatusInternalServerError)
+		return
+	}
+	for k := range items {
+		if !renderItem(w, r, &items[k]) {
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// SubscribeList upgrades the request to a websocket receiving the item mutations of the list
+// identified by the request url and the presence of other clients
+func (h *Handler) SubscribeList(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	h.subscribe(w, r, l.ID)
+}
+
+// Move assigns the item identified by the request url to the list from the request body
+// The list id 0 removes the item from its list, only the owner of the item can do it
+// Moving requires the editor role on both lists
+func (h *Handler) Move(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	var in struct {
+		ListID int `json:"listId"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	i, err := h.storage.GetItem(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested item.", http.StatusInternalServerError)
+		return
+	}
+	if i == nil {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) || !h.allowedOnList(w, r, in.ListID, list.RoleEditor) {
+		return
+	}
+	if in.ListID == 0 && i.OwnerID != currentUser(r).ID {
+		forbidden(w)
+		return
+	}
+
+	if err := h.storage.MoveItem(r.Context(), id, in.ListID); err != nil {
+		log.Println(err)
+		http.Error(w, "We could not move the item.", http.StatusInternalServerError)
+		return
+	}
+	from := i.ListID
+	i.ListID = in.ListID
+	h.publish(r, realtime.TypeItemMoved, *i)
+	if from != in.ListID {
+		// the clients of the previous list are notified as well
+		h.hub.Publish(topic(r, from), realtime.Message{Type: realtime.TypeItemMoved, ListID: i.ListID, ItemID: i.ID}, i)
+	}
+	writeJSON(w, http.StatusOK, i)
+}
+
+// Position moves the item identified by the request url in the manual order of its list, right before or right
+// after the other item of the request body `{"before":3}` or `{"after":3}`, only the rank of the item changes
+// Ordering the items of a shared list requires the editor role
+func (h *Handler) Position(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var in struct {
+		Before int `json:"before"`
+		After  int `json:"after"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if (in.Before == 0) == (in.After == 0) {
+		http.Error(w, "Either the before or the after item is required.", http.StatusBadRequest)
+		return
+	}
+	other := in.Before
+	if in.After != 0 {
+		other = in.After
+	}
+
+	found, err := h.storage.PositionItem(r.Context(), i.ID, other, in.After != 0)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not move the item.", http.StatusInternalServerError)
+		return
+	}
+	// the item was found above so it is the other item which is missing
+	if !found {
+		http.Error(w, "The other item does not exist in the same list.", http.StatusBadRequest)
+		return
+	}
+	i, ok = h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+	writeJSON(w, http.StatusOK, i)
+}
+
+// requestedList returns the list identified by the request url
+// When the list can not be returned the error response is already sent
+func (h *Handler) requestedList(w http.ResponseWriter, r *http.Request) (*list.List, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid list ID", http.StatusBadRequest)
+		return nil, false
+	}
+	l, err := h.storage.GetList(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested list.", http.StatusInternalServerError)
+		return nil, false
+	}
+	if l == nil {
+		http.Error(w, "List not found.", http.StatusNotFound)
+		return nil, false
+	}
+	return l, true
+}
+
+// allowedOnList checks that the user has at least the required role on the list
+// The list id 0 stands for the own items of the user which do not belong to any list
+// When the list does not exist or the role is not sufficient the error response is already sent
+func (h *Handler) allowedOnList(w http.ResponseWriter, r *http.Request, id int, required list.Role) bool {
+	if id == 0 {
+		return true
+	}
+	l, err := h.storage.GetList(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested list.", http.StatusInternalServerError)
+		return false
+	}
+	if l == nil {
+		http.Error(w, "List not found.", http.StatusBadRequest)
+		return false
+	}
+	if !l.Role.Allows(required) {
+		forbidden(w)
+		return false
+	}
+	return true
+}
+
+func forbidden(w http.ResponseWriter) {
+	http.Error(w, "You are not allowed to do this.", http.StatusForbidden)
+}
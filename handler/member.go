@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/repository"
+	"github.com/gorilla/mux"
+)
+
+// Members returns the users the list identified by the request url is shared with
+func (h *Handler) Members(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.storage.GetMembers(r.Context(), l.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the list members.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// AddMember shares the list identified by the request url with the user from the request body
+// Only the owner of the list can share it
+func (h *Handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+
+	var in list.Member
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.storage.GetUserByUsername(r.Context(), in.Username)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not share the list.", http.StatusInternalServerError)
+		return
+	}
+	if u == nil {
+		http.Error(w, "User not found.", http.StatusBadRequest)
+		return
+	}
+	// lists can only be shared inside their workspace
+	member, err := h.storage.IsWorkspaceMember(r.Context(), u.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not share the list.", http.StatusInternalServerError)
+		return
+	}
+	if !member {
+		http.Error(w, "User not found.", http.StatusBadRequest)
+		return
+	}
+	if u.ID == l.OwnerID {
+		http.Error(w, "The owner of the list can not be its member.", http.StatusBadRequest)
+		return
+	}
+
+	m := list.Member{UserID: u.ID, Username: u.Username, Role: in.Role}
+	err = h.storage.AddMember(r.Context(), l.ID, m)
+	if err == repository.ErrConflict {
+		http.Error(w, "The list is already shared with the user.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not share the list.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// UpdateMember changes the role of the member identified by the request url
+// Only the owner of the list can change the roles
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var in list.Member
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.UserID = userID
+	found, err := h.storage.UpdateMember(r.Context(), l.ID, in)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not change the role.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Member not found.", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember stops sharing the list identified by the request url with the user
+// The owner can remove any member, the other members can only leave the list
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) && userID != currentUser(r).ID {
+		forbidden(w)
+		return
+	}
+
+	found, err := h.storage.RemoveMember(r.Context(), l.ID, userID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not remove the member.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Member not found.", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/mention"
+	"github.com/aflog/todolist/realtime"
+	"github.com/aflog/todolist/workspace"
+)
+
+// Mentions returns the mentions of the current user, the newest first
+// Only the unread mentions are returned with the unread=true query parameter, limit is 1 to 100 and 50 by default
+func (h *Handler) Mentions(w http.ResponseWriter, r *http.Request) {
+	var unread bool
+	if v := r.URL.Query().Get("unread"); v != "" {
+		var err error
+		if unread, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid unread parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > mention.MaxLimit {
+			http.Error(w, "Invalid limit parameter, expected 1 to 100", http.StatusBadRequest)
+			return
+		}
+	}
+	mentions, err := h.storage.GetMentions(r.Context(), unread, limit)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the mentions.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, mentions)
+}
+
+// ReadMentions marks the mentions from the request body `{"ids":[1,2]}` as read and returns the number of marked mentions
+// Mentions of other users or which are already read are skipped
+func (h *Handler) ReadMentions(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		IDs []int `json:"ids"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(in.IDs) == 0 {
+		http.Error(w, "ids field is required and can not be empty", http.StatusBadRequest)
+		return
+	}
+	n, err := h.storage.ReadMentions(r.Context(), in.IDs)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the mentions.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		Updated int `json:"updated"`
+	}{n}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// notifyMentions stores the mentions added to the text of a comment, or of the description when commentID is zero,
+// and sends them to the clients of the mentioned users subscribed to /ws
+// The change of the text is already stored, a failure is only logged
+func (h *Handler) notifyMentions(r *http.Request, itemID int, commentID int, previous, text string) {
+	names := mention.Added(previous, text)
+	if len(names) == 0 {
+		return
+	}
+	mentions, err := h.storage.AddMentions(r.Context(), itemID, commentID, names)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	ws, _ := workspace.FromContext(r.Context())
+	for _, m := range mentions {
+		h.hub.Publish(realtime.UserTopic(ws.ID, m.UserID), realtime.Message{Type: realtime.TypeMention, ItemID: m.ItemID}, m)
+	}
+}
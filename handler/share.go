@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/share"
+	"github.com/gorilla/mux"
+)
+
+// Shares returns the share links created by the authenticated user, the tokens themselves are never returned
+func (h *Handler) Shares(w http.ResponseWriter, r *http.Request) {
+	shares, err := h.storage.GetShares(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the share links.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, shares)
+}
+
+// ShareItem creates a public read-only link to the item identified by the request url
+// Sharing an item of a shared list requires the editor role
+func (h *Handler) ShareItem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	i, err := h.storage.GetItem(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested item.", http.StatusInternalServerError)
+		return
+	}
+	if i == nil {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	h.createShare(w, r, share.Share{ItemID: i.ID})
+}
+
+// ShareList creates a public read-only link to the list identified by the request url and its items
+// Only the owner of the list can share it
+func (h *Handler) ShareList(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+	h.createShare(w, r, share.Share{ListID: l.ID})
+}
+
+// RevokeShare disables the share link identified by the request url
+func (h *Handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid share ID", http.StatusBadRequest)
+		return
+	}
+	found, err := h.storage.RevokeShare(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not revoke the share link.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Share link not found.", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Shared returns the item or the list with its items behind the token from the request url
+// It does not require authentication, unknown, expired and revoked tokens all return StatusNotFound
+func (h *Handler) Shared(w http.ResponseWriter, r *http.Request) {
+	s, err := h.storage.GetShareByHash(r.Context(), share.Hash(mux.Vars(r)["token"]))
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the shared content.", http.StatusInternalServerError)
+		return
+	}
+	if s == nil || !s.Active() {
+		http.Error(w, "Shared content not found.", http.StatusNotFound)
+		return
+	}
+
+	var response interface{}
+	if s.ItemID != 0 {
+		i, err := h.storage.GetSharedItem(r.Context(), *s)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not retrieve the shared content.", http.StatusInternalServerError)
+			return
+		}
+		if i == nil {
+			http.Error(w, "Shared content not found.", http.StatusNotFound)
+			return
+		}
+		response = struct {
+			Item interface{} `json:"item"`
+		}{i}
+	} else {
+		l, items, err := h.storage.GetSharedList(r.Context(), *s)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not retrieve the shared content.", http.StatusInternalServerError)
+			return
+		}
+		if l == nil {
+			http.Error(w, "Shared content not found.", http.StatusNotFound)
+			return
+		}
+		response = struct {
+			List  interface{} `json:"list"`
+			Items interface{} `json:"items"`
+		}{l, items}
+	}
+
+	if err := h.storage.CountShareAccess(r.Context(), s.ID); err != nil {
+		// the content is returned even when the access could not be counted
+		log.Println(err)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// createShare validates the expiration time from the request body, stores the share link
+// and returns it together with its token, the token can not be retrieved later
+func (h *Handler) createShare(w http.ResponseWriter, r *http.Request, s share.Share) {
+	var in share.Share
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	s.ExpiresAt = in.ExpiresAt
+	if err := s.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, hash, err := share.NewToken()
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new share link.", http.StatusInternalServerError)
+		return
+	}
+	s.Hash = hash
+	s.ID, err = h.storage.CreateShare(r.Context(), s)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new share link.", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		share.Share
+		Token string `json:"token"`
+		URL   string `json:"url"`
+	}{s, token, "/shared/" + token}
+	writeJSON(w, http.StatusCreated, response)
+}
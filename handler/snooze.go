@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+)
+
+// Snooze leaves the item identified by the request url out of the listings until the time of the request body
+// `{"until":"2021-05-15T13:11:50Z"}`, snoozing an item of a shared list requires the editor role
+func (h *Handler) Snooze(w http.ResponseWriter, r *http.Request) {
+	var in struct {
+		Until time.Time `json:"until"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !in.Until.After(time.Now()) {
+		http.Error(w, "The snooze must end in the future.", http.StatusBadRequest)
+		return
+	}
+	h.snooze(w, r, &in.Until)
+}
+
+// Unsnooze puts the snoozed item identified by the request url back in the listings
+// Unsnoozing an item of a shared list requires the editor role
+func (h *Handler) Unsnooze(w http.ResponseWriter, r *http.Request) {
+	h.snooze(w, r, nil)
+}
+
+func (h *Handler) snooze(w http.ResponseWriter, r *http.Request, until *time.Time) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	found, err := h.storage.SnoozeItem(r.Context(), i.ID, until)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not snooze the item.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return
+	}
+	i, ok = h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+	writeJSON(w, http.StatusOK, i)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/gorilla/mux"
+)
+
+// dateLayout is the layout of the dates of the time report period
+const dateLayout = "2006-01-02"
+
+// Time returns the estimate of the item identified by the request url, the time tracked on it and its time entries
+func (h *Handler) Time(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	entries, err := h.storage.GetTimeEntries(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the time entries.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		Estimate int              `json:"estimate"`
+		Tracked  int              `json:"tracked"`
+		Entries  []item.TimeEntry `json:"entries"`
+	}{i.Estimate, i.Tracked, entries}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// AddTimeEntry records the time of the request body `{"duration":1800,"note":"review"}` spent on the item identified
+// by the request url by the current user and returns its id, the entry ends now unless startedAt is set
+// Tracking time on an item of a shared list requires the editor role
+func (h *Handler) AddTimeEntry(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var e item.TimeEntry
+	if err := readJSON(r, &e); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := e.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e.ItemID = i.ID
+	id, err := h.storage.AddTimeEntry(r.Context(), e)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not add the time entry.", http.StatusInternalServerError)
+		return
+	}
+	h.publishItem(r, i.ID)
+
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// StartTimer starts a timer of the current user on the item identified by the request url and returns it
+// The timer of the user running on another item is stopped, tracking time on an item of a shared list requires
+// the editor role
+func (h *Handler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	e, err := h.storage.StartTimer(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not start the timer.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, e)
+}
+
+// StopTimer stops the timer of the current user running on the item identified by the request url and returns it
+func (h *Handler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	e, err := h.storage.StopTimer(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not stop the timer.", http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		http.Error(w, "No timer is running on the item.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	writeJSON(w, http.StatusOK, e)
+}
+
+// DeleteTimeEntry removes the time entry identified by the request url, the users only remove their own entries
+func (h *Handler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["tid"])
+	if err != nil {
+		http.Error(w, "Invalid time entry ID", http.StatusBadRequest)
+		return
+	}
+	found, err := h.storage.DeleteTimeEntry(r.Context(), i.ID, id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not delete the time entry.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Time entry not found.", http.StatusNotFound)
+		return
+	}
+	h.publishItem(r, i.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TimeReport sums the time tracked on the items visible to the user by label together with their estimates
+// The period is set by the from and to query parameters, two dates like 2021-05-15 which are both included,
+// it is the last 30 days by default
+func (h *Handler) TimeReport(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, to := today.AddDate(0, 0, -29), today
+	for name, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse(dateLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid "+name+" parameter, expected a date like 2021-05-15", http.StatusBadRequest)
+			return
+		}
+		*date = d
+	}
+	if to.Before(from) {
+		http.Error(w, "The from date can not be after the to date.", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.GetTimeReport(r.Context(), from, to.AddDate(0, 0, 1))
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the time report.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		From   string             `json:"from"`
+		To     string             `json:"to"`
+		Labels []item.TimeSummary `json:"labels"`
+	}{from.Format(dateLayout), to.Format(dateLayout), report}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+	"github.com/gorilla/mux"
+)
+
+// Trash returns the deleted items from the most recently deleted one
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.storage.GetTrash(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the trash.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// Restore takes the item identified by the request url out of the trash
+// Restoring an item of a shared list requires the editor role
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	i, err := h.storage.GetDeletedItem(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the requested item.", http.StatusInternalServerError)
+		return
+	}
+	if i == nil {
+		http.Error(w, "Item not found in the trash.", http.StatusNotFound)
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	found, err := h.storage.RestoreItem(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not restore the item.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Item not found in the trash.", http.StatusNotFound)
+		return
+	}
+	i.DeletedAt = nil
+	h.publish(r, realtime.TypeItemCreated, *i)
+	writeJSON(w, http.StatusOK, i)
+}
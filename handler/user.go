@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/user"
+)
+
+type credentials struct {
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	Workspace string `json:"workspace"`
+}
+
+// Register creates a new user account and returns its id in the http response
+// Returns StatusConflict if the username or the email is already taken
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	var in credentials
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	u := user.User{Username: in.Username, Email: in.Email}
+	if err := u.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := u.SetPassword(in.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.CreateUser(r.Context(), u)
+	if err == repository.ErrConflict {
+		http.Error(w, "Username or email is already taken.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new user.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// Login checks the username and password and returns a signed session token in the http response
+// The token is bound to the optional workspace from the request body, the user must be its member
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.signer == nil {
+		http.Error(w, "Authentication is not enabled.", http.StatusNotFound)
+		return
+	}
+	var in credentials
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.storage.GetUserByUsername(r.Context(), in.Username)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not log in the user.", http.StatusInternalServerError)
+		return
+	}
+	// the same response for an unknown user and a wrong password does not reveal existing usernames
+	if u == nil || !u.CheckPassword(in.Password) {
+		http.Error(w, "Invalid username or password.", http.StatusUnauthorized)
+		return
+	}
+
+	var workspaceID int
+	if in.Workspace != "" {
+		ws, err := h.storage.GetWorkspaceBySlug(user.NewContext(r.Context(), u), in.Workspace)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "We could not log in the user.", http.StatusInternalServerError)
+			return
+		}
+		if ws == nil || ws.Role == "" {
+			http.Error(w, "Workspace not found.", http.StatusNotFound)
+			return
+		}
+		workspaceID = ws.ID
+	}
+
+	token, expires, err := h.signer.Sign(u.ID, workspaceID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not log in the user.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}{token, expires}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Me returns the authenticated user in the http response
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	u, ok := user.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Authentication required.", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+	"github.com/gorilla/mux"
+)
+
+// Versions returns the versions of the item identified by the request url from the oldest one
+func (h *Handler) Versions(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	versions, err := h.storage.GetItemVersions(r.Context(), i.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the item versions.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// SelectVersion returns one version of the item identified by the request url
+func (h *Handler) SelectVersion(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	v, ok := h.requestedVersion(w, r, i)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// RestoreVersion replaces the item identified by the request url with one of its versions
+// The list of the item is kept and its comments are restored as well, the restored item is a new version
+// Restoring an item of a shared list requires the editor role
+func (h *Handler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+	v, ok := h.requestedVersion(w, r, i)
+	if !ok {
+		return
+	}
+	found, err := h.storage.RestoreItemVersion(r.Context(), i.ID, v.Number)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not restore the item version.", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return
+	}
+	i, ok = h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+	writeJSON(w, http.StatusOK, i)
+}
+
+// requestedVersion returns the version of the item identified by the request url
+// When the version can not be returned the error response is already sent
+func (h *Handler) requestedVersion(w http.ResponseWriter, r *http.Request, i *item.Item) (*item.Version, bool) {
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil {
+		http.Error(w, "Invalid version number", http.StatusBadRequest)
+		return nil, false
+	}
+	v, err := h.storage.GetItemVersion(r.Context(), i.ID, n)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the item version.", http.StatusInternalServerError)
+		return nil, false
+	}
+	if v == nil {
+		http.Error(w, "Version not found.", http.StatusNotFound)
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/realtime"
+	"github.com/aflog/todolist/repository"
+)
+
+// Workflow returns the states of the list identified by the request url and the transitions allowed between them
+func (h *Handler) Workflow(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	wf, err := h.storage.GetWorkflow(r.Context(), l.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the workflow.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, wf)
+}
+
+// UpdateWorkflow replaces the workflow of the list identified by the request url with the one from the request body
+// The states are matched by name, removing a state which still holds items is rejected with StatusConflict
+// Only the owner of the list can change its workflow
+func (h *Handler) UpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	if !l.Role.Allows(list.RoleOwner) {
+		forbidden(w)
+		return
+	}
+
+	var wf list.Workflow
+	if err := readJSON(r, &wf); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := wf.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changed, err := h.storage.UpdateWorkflow(r.Context(), l.ID, wf)
+	if err == repository.ErrStateInUse {
+		http.Error(w, "A removed state still holds items, move them to another state first.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the workflow.", http.StatusInternalServerError)
+		return
+	}
+	// the items of a state whose done flag changed follow it
+	for _, id := range changed {
+		h.publishItem(r, id)
+	}
+	h.Workflow(w, r)
+}
+
+// column of the board of a list
+type column struct {
+	list.State
+	Items []item.Item `json:"items"`
+}
+
+// Board returns the items of the list identified by the request url grouped by the states of its workflow
+// The columns follow the order of the states, the items are filtered like in ListItems
+func (h *Handler) Board(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.requestedList(w, r)
+	if !ok {
+		return
+	}
+	q, ok := itemQuery(w, r)
+	if !ok {
+		return
+	}
+	wf, err := h.storage.GetWorkflow(r.Context(), l.ID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the workflow.", http.StatusInternalServerError)
+		return
+	}
+	items, err := h.storage.GetListItems(r.Context(), l.ID, q)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the to do list items.", http.StatusInternalServerError)
+		return
+	}
+
+	columns := make([]column, len(wf.States))
+	for k, s := range wf.States {
+		columns[k] = column{State: s, Items: []item.Item{}}
+	}
+	for k := range items {
+		if !renderItem(w, r, &items[k]) {
+			return
+		}
+		// the items without a state are shown in the first state matching their status
+		s := wf.State(items[k].State)
+		if s == nil {
+			s = wf.Initial(items[k].Status)
+		}
+		for c := range columns {
+			if s != nil && columns[c].ID == s.ID {
+				columns[c].Items = append(columns[c].Items, items[k])
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, columns)
+}
+
+// ChangeState moves the item identified by the request url to the state of the request body `{"state":"Review"}`
+// The workflow of the list must allow the transition, changing the state requires the editor role
+func (h *Handler) ChangeState(w http.ResponseWriter, r *http.Request) {
+	i, ok := h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	if !h.allowedOnList(w, r, i.ListID, list.RoleEditor) {
+		return
+	}
+
+	var in struct {
+		State string `json:"state"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if in.State == "" {
+		http.Error(w, "The state is required.", http.StatusBadRequest)
+		return
+	}
+
+	next := *i
+	next.State = in.State
+	if !h.itemState(w, r, i.ListID, "", &next) {
+		return
+	}
+	if h.blockersRequired && next.Status && !i.Status && i.Blocked {
+		http.Error(w, "The item is blocked by items which are not done.", http.StatusConflict)
+		return
+	}
+	if !h.updateItem(w, r, next) {
+		return
+	}
+	i, ok = h.requestedItem(w, r)
+	if !ok {
+		return
+	}
+	h.publish(r, realtime.TypeItemUpdated, *i)
+	writeJSON(w, http.StatusOK, i)
+}
+
+// itemState sets the status of the item to the done flag of its state in the workflow of the list
+// The state is only looked up when it differs from the previous one
+// When the state is not part of the workflow the error response is already sent
+func (h *Handler) itemState(w http.ResponseWriter, r *http.Request, listID int, previous string, i *item.Item) bool {
+	if i.State == "" || i.State == previous {
+		return true
+	}
+	if listID == 0 {
+		http.Error(w, "Only the items of a list have a state.", http.StatusBadRequest)
+		return false
+	}
+	wf, err := h.storage.GetWorkflow(r.Context(), listID)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the workflow.", http.StatusInternalServerError)
+		return false
+	}
+	s := wf.State(i.State)
+	if s == nil {
+		http.Error(w, "Unknown state "+i.State, http.StatusBadRequest)
+		return false
+	}
+	i.Status = s.Done
+	return true
+}
+
+// updateItem stores the item, when the item can not be stored the error response is already sent
+func (h *Handler) updateItem(w http.ResponseWriter, r *http.Request, i item.Item) bool {
+	found, err := h.storage.UpdateItem(r.Context(), i)
+	if err == repository.ErrTransition {
+		http.Error(w, "The workflow of the list does not allow moving the item to "+i.State+".", http.StatusConflict)
+		return false
+	}
+	if err == repository.ErrUnknownState {
+		http.Error(w, "Unknown state "+i.State, http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not update the item.", http.StatusInternalServerError)
+		return false
+	}
+	if !found {
+		http.Error(w, "Item not found.", http.StatusNotFound)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/workspace"
+)
+
+// Workspaces returns the workspaces the authenticated user is a member of
+func (h *Handler) Workspaces(w http.ResponseWriter, r *http.Request) {
+	workspaces, err := h.storage.GetWorkspaces(r.Context())
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not retrieve the workspaces.", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, workspaces)
+}
+
+// AddWorkspace creates a new workspace administrated by the authenticated user
+// Returns StatusConflict if the slug is already taken
+func (h *Handler) AddWorkspace(w http.ResponseWriter, r *http.Request) {
+	var in workspace.Workspace
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := in.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.storage.CreateWorkspace(r.Context(), in)
+	if err == repository.ErrConflict {
+		http.Error(w, "The workspace slug is already taken.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not create new workspace.", http.StatusInternalServerError)
+		return
+	}
+	response := struct {
+		ID int `json:"id"`
+	}{id}
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// AddWorkspaceMember adds the user from the request body to the workspace of the request
+// Only the admins of the workspace can add members
+func (h *Handler) AddWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	ws, _ := workspace.FromContext(r.Context())
+	if ws == nil || ws.Role != workspace.RoleAdmin {
+		forbidden(w)
+		return
+	}
+
+	var in struct {
+		Username string         `json:"username"`
+		Role     workspace.Role `json:"role"`
+	}
+	if err := readJSON(r, &in); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if in.Role == "" {
+		in.Role = workspace.RoleMember
+	}
+	if in.Role != workspace.RoleMember && in.Role != workspace.RoleAdmin {
+		http.Error(w, "workspace: role must be member or admin", http.StatusBadRequest)
+		return
+	}
+
+	u, err := h.storage.GetUserByUsername(r.Context(), in.Username)
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not add the member.", http.StatusInternalServerError)
+		return
+	}
+	if u == nil {
+		http.Error(w, "User not found.", http.StatusBadRequest)
+		return
+	}
+
+	err = h.storage.AddWorkspaceMember(r.Context(), u.ID, in.Role)
+	if err == repository.ErrConflict {
+		http.Error(w, "The user is already a member of the workspace.", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		http.Error(w, "We could not add the member.", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
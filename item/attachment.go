@@ -0,0 +1,43 @@
+package item
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Attachment defines the structure of a file attached to an item
+// The content is kept in a blob store under Key, the same content attached twice is stored once
+type Attachment struct {
+	ID          int       `json:"id"`
+	ItemID      int       `json:"itemId"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType"`
+	Size        int64     `json:"size"`
+	UploaderID  int       `json:"uploaderId"`
+	Uploader    string    `json:"uploader"`
+	Key         string    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Validate cleans the file name sent by the client and checks it is usable
+// Directories and control characters are removed so the name is safe to send back in a header
+func (a *Attachment) Validate() error {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, filepath.Base(strings.ReplaceAll(a.Name, `\`, "/")))
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return errors.New("attachment: file name is required and can not be empty")
+	}
+	if len(name) > 255 {
+		return errors.New("attachment: file name can not be longer than 255 characters")
+	}
+	a.Name = name
+	return nil
+}
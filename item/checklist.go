@@ -0,0 +1,53 @@
+package item
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// MaxChecklist is the largest number of entries in the checklist of one item
+const MaxChecklist = 100
+
+// ChecklistEntry defines the structure of one step in the checklist of an item
+// The entries of a checklist are ordered, new entries are added at its end
+type ChecklistEntry struct {
+	ID        int       `json:"id"`
+	ItemID    int       `json:"-"`
+	Text      string    `json:"text"`
+	Checked   bool      `json:"checked"`
+	UpdatedAt time.Time `json:"-"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Progress counts the checked entries of a checklist
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Validate that the entry has a text
+func (c *ChecklistEntry) Validate() error {
+	c.Text = strings.TrimSpace(c.Text)
+	if c.Text == "" {
+		return errors.New("checklist: text field is required and can not be empty")
+	}
+	if len(c.Text) > 500 {
+		return errors.New("checklist: text can not be longer than 500 characters")
+	}
+	return nil
+}
+
+// ChecklistProgress returns the progress of the checklist, nil for an empty checklist
+func ChecklistProgress(checklist []ChecklistEntry) *Progress {
+	if len(checklist) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(checklist)}
+	for _, c := range checklist {
+		if c.Checked {
+			p.Done++
+		}
+	}
+	return p
+}
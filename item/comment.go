@@ -0,0 +1,40 @@
+package item
+
+import "time"
+
+// CommentEdit holds the text of a comment before one of its edits
+type CommentEdit struct {
+	Text     string    `json:"text"`
+	EditedAt time.Time `json:"editedAt"`
+}
+
+// Thread nests the replies of the comments under the comment they answer
+// The comments keep their order, replies to comments which are not present are returned at the top level
+func Thread(comments []Comment) []Comment {
+	children := make(map[int][]Comment)
+	known := make(map[int]bool, len(comments))
+	for _, c := range comments {
+		known[c.ID] = true
+	}
+	var top []Comment
+	for _, c := range comments {
+		if c.ParentID != 0 && known[c.ParentID] {
+			children[c.ParentID] = append(children[c.ParentID], c)
+			continue
+		}
+		top = append(top, c)
+	}
+
+	var nest func(c Comment) Comment
+	nest = func(c Comment) Comment {
+		for _, reply := range children[c.ID] {
+			c.Replies = append(c.Replies, nest(reply))
+		}
+		return c
+	}
+	threads := make([]Comment, len(top))
+	for k, c := range top {
+		threads[k] = nest(c)
+	}
+	return threads
+}
@@ -0,0 +1,16 @@
+package item
+
+// Dependency defines the structure of an item which blocks or is blocked by another item
+type Dependency struct {
+	ID     int    `json:"id"`
+	ListID int    `json:"listId"`
+	Title  string `json:"title"`
+	Status bool   `json:"status"`
+}
+
+// Dependencies holds the items an item is blocked by and the items it blocks
+// An item is blocked while one of the items it is blocked by is not done
+type Dependencies struct {
+	BlockedBy []Dependency `json:"blockedBy"`
+	Blocks    []Dependency `json:"blocks"`
+}
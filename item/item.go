@@ -2,38 +2,73 @@ package item
 
 import (
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/aflog/todolist/label"
 )
 
 // Item defines the structure of an to do list task
 type Item struct {
-	ID          int       `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Labels      []Label   `json:"labels"`
-	Comments    []Comment `json:"comments"`
-	Status      bool      `json:"status"`
-	DueDate     time.Time `json:"dueDate"`
-	UpdatedAt   time.Time `json:"-"`
-	CreatedAt   time.Time `json:"-"`
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"-"`
+	OwnerID     int    `json:"ownerId"`
+	ListID      int    `json:"listId"`
+	Title       string `json:"title"`
+	// Description is written in Markdown, DescriptionHTML is its sanitized HTML, only set when it is requested
+	Description     string           `json:"description"`
+	DescriptionHTML string           `json:"descriptionHtml,omitempty"`
+	Labels          []Label          `json:"labels"`
+	Comments        []Comment        `json:"comments"`
+	Checklist       []ChecklistEntry `json:"checklist,omitempty"`
+	// Progress is nil when the checklist is empty
+	Progress *Progress `json:"progress,omitempty"`
+	// Status is set while the State in the workflow of the list is a done one
+	Status bool   `json:"status"`
+	State  string `json:"state,omitempty"`
+	// Position is the rank of the item in the manual order of its list, or of the own items without a list
+	Position string `json:"position,omitempty"`
+	// Blocked is set while an item it depends on is not done
+	Blocked bool      `json:"blocked,omitempty"`
+	DueDate time.Time `json:"dueDate"`
+	// the item is left out of the listings until its start date and while it is snoozed, see Deferred
+	StartDate    *time.Time `json:"startDate,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozedUntil,omitempty"`
+	// Estimate is the expected time to spend on the item and Tracked the time spent on it, in seconds
+	Estimate    int        `json:"estimate,omitempty"`
+	Tracked     int        `json:"tracked,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ArchivedAt  *time.Time `json:"archivedAt,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+	UpdatedAt   time.Time  `json:"-"`
+	CreatedAt   time.Time  `json:"-"`
 }
 
 // Label defines the structure of a label used in to do list tasks
+// Labels are shared by the items of a workspace, the ID and the color are the ones of the shared label
+// and Text is its name
 type Label struct {
 	ID        int       `json:"id"`
 	ItemID    int       `json:"-"`
 	Text      string    `json:"text"`
+	Color     string    `json:"color,omitempty"`
 	UpdatedAt time.Time `json:"-"`
 	CreatedAt time.Time `json:"-"`
 }
 
 // Comment defines the structure of a comment used in to do list tasks
+// A reply refers to the comment it answers by ParentID, Edited marks the comments whose text was replaced
 type Comment struct {
 	ID        int       `json:"id"`
 	ItemID    int       `json:"-"`
+	AuthorID  int       `json:"authorId,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	ParentID  int       `json:"parentId,omitempty"`
 	Text      string    `json:"text"`
-	UpdatedAt time.Time `json:"-"`
-	CreatedAt time.Time `json:"-"`
+	Edited    bool      `json:"edited,omitempty"`
+	Replies   []Comment `json:"replies,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 //Validate that all required field are present
@@ -41,5 +76,50 @@ func (i *Item) Validate() error {
 	if i.Title == "" {
 		return errors.New("item: title field is required and can not be empty")
 	}
+	for k, l := range i.Labels {
+		name, err := label.Normalize(l.Text)
+		if err != nil {
+			return err
+		}
+		if len(name) > label.MaxNameLength {
+			return errors.New("item: labels can not be longer than 100 characters")
+		}
+		i.Labels[k].Text = name
+	}
+	if i.StartDate != nil && !i.DueDate.IsZero() && i.StartDate.After(i.DueDate) {
+		return errors.New("item: the start date can not be after the due date")
+	}
+	if i.Estimate < 0 {
+		return errors.New("item: the estimate can not be negative")
+	}
+	if len(i.Checklist) > MaxChecklist {
+		return errors.New("item: the checklist can not have more than 100 entries")
+	}
+	for k := range i.Checklist {
+		if err := i.Checklist[k].Validate(); err != nil {
+			return err
+		}
+	}
+	for k := range i.Comments {
+		if err := i.Comments[k].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deferred reports whether the item has not started yet or is snoozed at the provided time
+func (i *Item) Deferred(now time.Time) bool {
+	return (i.StartDate != nil && i.StartDate.After(now)) || (i.SnoozedUntil != nil && i.SnoozedUntil.After(now))
+}
+
+//Validate that the comment has a text
+func (c *Comment) Validate() error {
+	if strings.TrimSpace(c.Text) == "" {
+		return errors.New("comment: text field is required and can not be empty")
+	}
+	if len(c.Text) > 500 {
+		return errors.New("comment: text can not be longer than 500 characters")
+	}
 	return nil
 }
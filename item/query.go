@@ -0,0 +1,21 @@
+package item
+
+// Query defines the options of the item listings, the zero value lists the open and done items
+// which are not archived
+type Query struct {
+	// IncludeArchived lists the archived items as well
+	IncludeArchived bool
+	// IncludeDeferred lists the items which have not started yet or are snoozed as well
+	IncludeDeferred bool
+	// Labels lists only the items tagged with all the labels, a label ending with /* matches the label
+	// and all the labels below it, e.g. client/acme/* matches client/acme and client/acme/billing
+	Labels []string
+	// Sort orders the items by SortPosition, they are in the order they were created when it is empty
+	Sort string
+}
+
+// SortPosition orders the items in the manual order of their list, see Item.Position
+const SortPosition = "position"
+
+// Wildcard ends the labels of a query matching all the labels below them
+const Wildcard = "/*"
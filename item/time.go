@@ -0,0 +1,50 @@
+package item
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxTimeEntry is the longest time entry, in seconds
+const MaxTimeEntry = 24 * 60 * 60
+
+// TimeEntry defines the time a user spent on an item, in seconds
+// A running timer has no EndedAt, its Duration is the time elapsed since StartedAt
+type TimeEntry struct {
+	ID        int        `json:"id"`
+	ItemID    int        `json:"itemId"`
+	UserID    int        `json:"userId"`
+	User      string     `json:"user"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	Duration  int        `json:"duration"`
+	Note      string     `json:"note"`
+}
+
+// TimeSummary sums the time tracked on the items with a label and their estimates, in seconds
+// The items without a label are summed with an empty Label, an item with several labels is counted for each of them
+type TimeSummary struct {
+	Label     string `json:"label"`
+	Items     int    `json:"items"`
+	Tracked   int    `json:"tracked"`
+	Estimated int    `json:"estimated"`
+}
+
+// Validate that a time entry recorded manually has a duration and starts in the past
+func (e *TimeEntry) Validate() error {
+	if e.Duration <= 0 || e.Duration > MaxTimeEntry {
+		return errors.New("time: duration field is required and must be between 1 second and 24 hours")
+	}
+	if len(e.Note) > 200 {
+		return errors.New("time: note can not be longer than 200 characters")
+	}
+	if e.StartedAt.IsZero() {
+		e.StartedAt = time.Now().Add(-time.Duration(e.Duration) * time.Second)
+	}
+	if e.StartedAt.After(time.Now()) {
+		return errors.New("time: an entry can not start in the future")
+	}
+	ended := e.StartedAt.Add(time.Duration(e.Duration) * time.Second)
+	e.EndedAt = &ended
+	return nil
+}
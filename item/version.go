@@ -0,0 +1,13 @@
+package item
+
+import "time"
+
+// Version defines the structure of a snapshot of an item taken after each change
+// Versions of an item are numbered from 1 in the order of the changes
+type Version struct {
+	Number    int       `json:"number"`
+	ActorID   int       `json:"actorId"`
+	Actor     string    `json:"actor"`
+	Item      Item      `json:"item"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,27 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Func is one execution of a periodic job
+type Func func(ctx context.Context) error
+
+// Every runs fn immediately and then every interval until the context is done
+// A failed execution is logged and the job runs again at the next interval
+func Every(ctx context.Context, name string, interval time.Duration, fn Func) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := fn(ctx); err != nil {
+			log.Printf("job %s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package label
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// MaxNameLength is the longest label name
+const MaxNameLength = 100
+
+// MaxSuggestions is the largest number of labels suggested at once
+const MaxSuggestions = 50
+
+// Label defines the structure of a label shared by the items of a workspace
+// Items is the number of items tagged with the label, it is not stored with the label
+// Uses counts every time an item was tagged with the label and LastUsedAt is the time of the last one
+type Label struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Color       string     `json:"color"`
+	Description string     `json:"description"`
+	Items       int        `json:"items"`
+	Uses        int        `json:"uses"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// Validate that all required field are present and the color is a hex color like #00ff00
+// The name is normalized, see Normalize
+func (l *Label) Validate() error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("label: name field is required and can not be empty")
+	}
+	name, err := Normalize(l.Name)
+	if err != nil {
+		return err
+	}
+	l.Name = name
+	if len(l.Name) > MaxNameLength {
+		return errors.New("label: name can not be longer than 100 characters")
+	}
+	if l.Color != "" && !colorPattern.MatchString(l.Color) {
+		return errors.New("label: color field must be a hex color like #00ff00")
+	}
+	return nil
+}
+
+// Separator splits the label names into the path of a hierarchy like client/acme/billing
+const Separator = "/"
+
+// Normalize returns the label name with the spaces around each level of the path removed
+// A path with an empty level like client//billing is rejected
+func Normalize(name string) (string, error) {
+	levels := strings.Split(strings.TrimSpace(name), Separator)
+	for k, level := range levels {
+		levels[k] = strings.TrimSpace(level)
+		if levels[k] == "" {
+			return "", errors.New("label: name can not have an empty level")
+		}
+	}
+	return strings.Join(levels, Separator), nil
+}
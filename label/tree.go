@@ -0,0 +1,59 @@
+package label
+
+import (
+	"sort"
+	"strings"
+)
+
+// Node defines the structure of one level of the label hierarchy
+// LabelID is 0 for the levels which are not labels themselves, e.g. client for client/acme
+// OpenItems counts the open items tagged with the label of the node or of any node below it
+type Node struct {
+	Name      string  `json:"name"`
+	Path      string  `json:"path"`
+	LabelID   int     `json:"labelId,omitempty"`
+	OpenItems int     `json:"openItems"`
+	Children  []*Node `json:"children"`
+	items     map[int]struct{}
+}
+
+// Tree builds the label hierarchy from the labels and the ids of the open items tagged with each label
+// An item tagged with several labels below a node is counted once for that node
+func Tree(labels []Label, openItems map[int][]int) []*Node {
+	root := &Node{Children: []*Node{}}
+	for _, l := range labels {
+		node := root
+		path := []string{}
+		for _, level := range strings.Split(l.Name, Separator) {
+			path = append(path, level)
+			node = node.child(level, strings.Join(path, Separator))
+			for _, id := range openItems[l.ID] {
+				node.items[id] = struct{}{}
+			}
+		}
+		node.LabelID = l.ID
+	}
+	root.finish()
+	return root.Children
+}
+
+// child returns the child node with the provided name and creates it when it doesn't exist yet
+func (n *Node) child(name string, path string) *Node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &Node{Name: name, Path: path, Children: []*Node{}, items: make(map[int]struct{})}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// finish counts the open items and sorts the children by name
+func (n *Node) finish() {
+	n.OpenItems = len(n.items)
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	for _, c := range n.Children {
+		c.finish()
+	}
+}
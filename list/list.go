@@ -0,0 +1,34 @@
+package list
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// List defines the structure of a list grouping to do list tasks
+// Role is the role of the requesting user, it is not stored with the list
+type List struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Color       string    `json:"color"`
+	Archived    bool      `json:"archived"`
+	OwnerID     int       `json:"ownerId"`
+	Role        Role      `json:"role"`
+	UpdatedAt   time.Time `json:"-"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+// Validate that all required field are present and the color is a hex color like #00ff00
+func (l *List) Validate() error {
+	if l.Name == "" {
+		return errors.New("list: name field is required and can not be empty")
+	}
+	if l.Color != "" && !colorPattern.MatchString(l.Color) {
+		return errors.New("list: color field must be a hex color like #00ff00")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package list
+
+import (
+	"errors"
+	"time"
+)
+
+// Role defines what a user is allowed to do with a list and its items
+type Role string
+
+// Roles ordered from the least to the most privileged
+const (
+	RoleViewer    Role = "viewer"
+	RoleCommenter Role = "commenter"
+	RoleEditor    Role = "editor"
+	RoleOwner     Role = "owner"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:    1,
+	RoleCommenter: 2,
+	RoleEditor:    3,
+	RoleOwner:     4,
+}
+
+// Valid reports whether the role can be given to a member, the owner role can not be shared
+func (r Role) Valid() bool {
+	return r == RoleViewer || r == RoleCommenter || r == RoleEditor
+}
+
+// Allows reports whether the role has at least the privileges of the required one
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// CanComment reports whether the role allows to comment the items
+func (r Role) CanComment() bool {
+	return r.Allows(RoleCommenter)
+}
+
+// CanEdit reports whether the role allows to create, change and move the items
+func (r Role) CanEdit() bool {
+	return r.Allows(RoleEditor)
+}
+
+// Member defines the structure of a user the list is shared with
+type Member struct {
+	UserID    int       `json:"userId"`
+	Username  string    `json:"username"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Validate that the role can be given to a member
+func (m *Member) Validate() error {
+	if !m.Role.Valid() {
+		return errors.New("member: role must be one of viewer, commenter or editor")
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package list
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxStates is the largest number of states of a workflow
+const MaxStates = 20
+
+// State is a column of the board of a list, the items in a done state have their status set
+type State struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Done bool   `json:"done"`
+}
+
+// Transition allows moving the items of a list from one state to another one, the states are referred to by name
+type Transition struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Workflow defines the ordered states of the items of a list and the allowed transitions between them
+// A workflow without transitions allows moving the items between any states
+type Workflow struct {
+	States      []State      `json:"states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// DefaultWorkflow returns the workflow of the new lists, Backlog -> In progress -> Review -> Done
+// An item can be moved one step forward or back, a done item can be reopened in progress
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		States: []State{
+			{Name: "Backlog"},
+			{Name: "In progress"},
+			{Name: "Review"},
+			{Name: "Done", Done: true},
+		},
+		Transitions: []Transition{
+			{From: "Backlog", To: "In progress"},
+			{From: "In progress", To: "Backlog"},
+			{From: "In progress", To: "Review"},
+			{From: "Review", To: "In progress"},
+			{From: "Review", To: "Done"},
+			{From: "Done", To: "In progress"},
+		},
+	}
+}
+
+// Validate that the states have unique names, at least one of them is done and one is not,
+// and that the transitions refer to the states
+func (w *Workflow) Validate() error {
+	if len(w.States) > MaxStates {
+		return errors.New("workflow: a workflow can not have more than 20 states")
+	}
+	names := make(map[string]bool)
+	var done, open bool
+	for k, s := range w.States {
+		name := strings.TrimSpace(s.Name)
+		if name == "" || len(name) > 50 {
+			return errors.New("workflow: state names are required and can not be longer than 50 characters")
+		}
+		if names[strings.ToLower(name)] {
+			return errors.New("workflow: state names must be unique")
+		}
+		names[strings.ToLower(name)] = true
+		w.States[k].Name = name
+		done = done || s.Done
+		open = open || !s.Done
+	}
+	if !done || !open {
+		return errors.New("workflow: a workflow needs at least one done state and one state which is not done")
+	}
+	for _, t := range w.Transitions {
+		if w.State(t.From) == nil || w.State(t.To) == nil {
+			return errors.New("workflow: transitions must refer to the states of the workflow")
+		}
+		if t.From == t.To {
+			return errors.New("workflow: a transition can not lead to the state it starts from")
+		}
+	}
+	return nil
+}
+
+// State returns the state with the name and nil if there is no such state
+func (w *Workflow) State(name string) *State {
+	for k := range w.States {
+		if w.States[k].Name == name {
+			return &w.States[k]
+		}
+	}
+	return nil
+}
+
+// Initial returns the first state which is done or not, nil if the workflow has no such state
+func (w *Workflow) Initial(done bool) *State {
+	for k := range w.States {
+		if w.States[k].Done == done {
+			return &w.States[k]
+		}
+	}
+	return nil
+}
+
+// Allows reports whether an item can be moved from the state to the other one
+func (w *Workflow) Allows(from, to string) bool {
+	if len(w.Transitions) == 0 {
+		return true
+	}
+	for _, t := range w.Transitions {
+		if t.From == from && t.To == to {
+			return true
+		}
+	}
+	return false
+}
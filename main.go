@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
@@ -8,7 +9,11 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/aflog/todolist/auth"
+	"github.com/aflog/todolist/blob"
 	"github.com/aflog/todolist/handler"
+	"github.com/aflog/todolist/job"
+	"github.com/aflog/todolist/realtime"
 	"github.com/aflog/todolist/repository/mysql"
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
@@ -48,6 +53,22 @@ type Config struct {
 	MysqlHost   string `mapstructure:"MYSQL_HOST"`
 	MysqlPort   string `mapstructure:"MYSQL_PORT"`
 	MysqlBDName string `mapstructure:"MYSQL_DB_NAME"`
+	// AuthSecret signs the session tokens, it must have at least 32 characters
+	AuthSecret string `mapstructure:"AUTH_SECRET"`
+	// TokenTTL is the validity of a session token, 24h if not set
+	TokenTTL time.Duration `mapstructure:"AUTH_TOKEN_TTL"`
+	// BaseDomain enables selecting the workspace by subdomain, e.g. acme.<BaseDomain>
+	BaseDomain string `mapstructure:"BASE_DOMAIN"`
+	// TrashRetention is how long the deleted items are kept before they are purged, 720h if not set
+	TrashRetention time.Duration `mapstructure:"TRASH_RETENTION"`
+	// ArchiveAfter is how long the done items stay in the listings before they are archived, 336h if not set
+	ArchiveAfter time.Duration `mapstructure:"ARCHIVE_AFTER"`
+	// AttachmentsDir is the directory storing the content of the attachments, attachments if not set
+	AttachmentsDir string `mapstructure:"ATTACHMENTS_DIR"`
+	// AttachmentMaxSize is the largest attachment in bytes, 10MiB if not set
+	AttachmentMaxSize int64 `mapstructure:"ATTACHMENT_MAX_SIZE"`
+	// RequireBlockersDone rejects marking an item done while an item it is blocked by is not done
+	RequireBlockersDone bool `mapstructure:"REQUIRE_BLOCKERS_DONE"`
 }
 
 // LoadConfig creates the configuration from flags, env and file.
@@ -80,6 +101,9 @@ func LoadConfig() (c Config, err error) {
 type App struct {
 	conf   Config
 	db     *sql.DB
+	repo   *mysql.Repository
+	blobs  blob.Store
+	hub    *realtime.Hub
 	router *mux.Router
 }
 
@@ -107,9 +131,39 @@ func (a *App) Initialize(c Config) error {
 		return err
 	}
 
+	// set up the signing of the session tokens
+	ttl := a.conf.TokenTTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	signer, err := auth.NewSigner(a.conf.AuthSecret, ttl)
+	if err != nil {
+		return err
+	}
+
+	// set up the storage of the attachments
+	dir := a.conf.AttachmentsDir
+	if dir == "" {
+		dir = "attachments"
+	}
+	a.blobs, err = blob.NewLocalStore(dir)
+	if err != nil {
+		return err
+	}
+	maxSize := a.conf.AttachmentMaxSize
+	if maxSize == 0 {
+		maxSize = 10 << 20
+	}
+
 	// get repository for list items
 	sqlRepo := mysql.NewRepository(a.db)
-	itemsHandler, err := handler.New(sqlRepo)
+	a.repo = sqlRepo
+	a.hub = realtime.NewHub()
+	opts := []handler.Option{handler.WithHub(a.hub), handler.WithSigner(signer), handler.WithBlobStore(a.blobs, maxSize)}
+	if a.conf.RequireBlockersDone {
+		opts = append(opts, handler.WithBlockersRequired())
+	}
+	itemsHandler, err := handler.New(sqlRepo, opts...)
 	if err != nil {
 		return err
 	}
@@ -117,16 +171,172 @@ func (a *App) Initialize(c Config) error {
 	log.Println("Starting Todolist API server")
 	a.router = mux.NewRouter()
 	a.router.HandleFunc("/health", Health).Methods("GET")
+	a.router.HandleFunc("/users", itemsHandler.Register).Methods("POST")
+	a.router.HandleFunc("/login", itemsHandler.Login).Methods("POST")
+	a.router.HandleFunc("/shared/{token}", itemsHandler.Shared).Methods("GET")
+
+	// all the other routes require an authenticated user
+	api := a.router.PathPrefix("/").Subrouter()
+	api.Use(auth.NewMiddleware(signer, sqlRepo).Authenticate, auth.RequireScopes, auth.NewWorkspaceResolver(sqlRepo, a.conf.BaseDomain).Resolve)
+	api.HandleFunc("/me", itemsHandler.Me).Methods("GET")
+	api.HandleFunc("/me/mentions", itemsHandler.Mentions).Methods("GET")
+	api.HandleFunc("/me/mentions/read", itemsHandler.ReadMentions).Methods("POST")
+	api.HandleFunc("/me/keys", itemsHandler.APIKeys).Methods("GET")
+	api.HandleFunc("/me/keys", itemsHandler.AddAPIKey).Methods("POST")
+	api.HandleFunc("/me/keys/{id}", itemsHandler.RevokeAPIKey).Methods("DELETE")
+	api.HandleFunc("/workspaces", itemsHandler.Workspaces).Methods("GET")
+	api.HandleFunc("/workspaces", itemsHandler.AddWorkspace).Methods("POST")
+	api.HandleFunc("/workspace/members", itemsHandler.AddWorkspaceMember).Methods("POST")
 	// TODO router.HandleFunc("/items/{id}/done", ToggleDone).Methods("POST")
-	a.router.HandleFunc("/items/{id}", itemsHandler.Select).Methods("GET")
-	// TODO router.HandleFunc("/items/{id}", itemsHandler.Update).Methods("UPDATE")
-	a.router.HandleFunc("/items", itemsHandler.List).Methods("GET")
-	a.router.HandleFunc("/items", itemsHandler.Add).Methods("POST")
+	api.HandleFunc("/items/{id}", itemsHandler.Select).Methods("GET")
+	api.HandleFunc("/items/{id}", itemsHandler.Update).Methods("PUT")
+	api.HandleFunc("/items/{id}", itemsHandler.Delete).Methods("DELETE")
+	api.HandleFunc("/items", itemsHandler.List).Methods("GET")
+	api.HandleFunc("/items", itemsHandler.Add).Methods("POST")
+	api.HandleFunc("/items/archive", itemsHandler.Archive).Methods("POST")
+	api.HandleFunc("/items/unarchive", itemsHandler.Unarchive).Methods("POST")
+	api.HandleFunc("/items/{id}/move", itemsHandler.Move).Methods("POST")
+	api.HandleFunc("/items/{id}/position", itemsHandler.Position).Methods("POST")
+	api.HandleFunc("/items/{id}/restore", itemsHandler.Restore).Methods("POST")
+	api.HandleFunc("/items/{id}/snooze", itemsHandler.Snooze).Methods("POST")
+	api.HandleFunc("/items/{id}/snooze", itemsHandler.Unsnooze).Methods("DELETE")
+	api.HandleFunc("/trash", itemsHandler.Trash).Methods("GET")
+	api.HandleFunc("/items/{id}/shares", itemsHandler.ShareItem).Methods("POST")
+	api.HandleFunc("/items/{id}/comments", itemsHandler.Comments).Methods("GET")
+	api.HandleFunc("/items/{id}/comments", itemsHandler.AddComment).Methods("POST")
+	api.HandleFunc("/items/{id}/comments/{cid}", itemsHandler.UpdateComment).Methods("PUT")
+	api.HandleFunc("/items/{id}/comments/{cid}", itemsHandler.DeleteComment).Methods("DELETE")
+	api.HandleFunc("/items/{id}/comments/{cid}/edits", itemsHandler.CommentEdits).Methods("GET")
+	api.HandleFunc("/items/{id}/checklist", itemsHandler.AddChecklistEntry).Methods("POST")
+	api.HandleFunc("/items/{id}/checklist/order", itemsHandler.ReorderChecklist).Methods("PUT")
+	api.HandleFunc("/items/{id}/checklist/{cid}/toggle", itemsHandler.ToggleChecklistEntry).Methods("POST")
+	api.HandleFunc("/items/{id}/checklist/{cid}", itemsHandler.DeleteChecklistEntry).Methods("DELETE")
+	api.HandleFunc("/items/{id}/attachments", itemsHandler.Attachments).Methods("GET")
+	api.HandleFunc("/items/{id}/attachments", itemsHandler.AddAttachment).Methods("POST")
+	api.HandleFunc("/items/{id}/attachments/{aid}", itemsHandler.DownloadAttachment).Methods("GET")
+	api.HandleFunc("/items/{id}/attachments/{aid}", itemsHandler.DeleteAttachment).Methods("DELETE")
+	api.HandleFunc("/items/{id}/state", itemsHandler.ChangeState).Methods("PUT")
+	api.HandleFunc("/items/{id}/time", itemsHandler.Time).Methods("GET")
+	api.HandleFunc("/items/{id}/time", itemsHandler.AddTimeEntry).Methods("POST")
+	api.HandleFunc("/items/{id}/time/start", itemsHandler.StartTimer).Methods("POST")
+	api.HandleFunc("/items/{id}/time/stop", itemsHandler.StopTimer).Methods("POST")
+	api.HandleFunc("/items/{id}/time/{tid}", itemsHandler.DeleteTimeEntry).Methods("DELETE")
+	api.HandleFunc("/items/{id}/dependencies", itemsHandler.Dependencies).Methods("GET")
+	api.HandleFunc("/items/{id}/dependencies", itemsHandler.AddDependency).Methods("POST")
+	api.HandleFunc("/items/{id}/dependencies/{blockerId}", itemsHandler.RemoveDependency).Methods("DELETE")
+	api.HandleFunc("/items/{id}/history", itemsHandler.History).Methods("GET")
+	api.HandleFunc("/items/{id}/versions", itemsHandler.Versions).Methods("GET")
+	api.HandleFunc("/items/{id}/versions/{n}", itemsHandler.SelectVersion).Methods("GET")
+	api.HandleFunc("/items/{id}/versions/{n}/restore", itemsHandler.RestoreVersion).Methods("POST")
+	api.HandleFunc("/audit", itemsHandler.Audit).Methods("GET")
+	api.HandleFunc("/reports/time", itemsHandler.TimeReport).Methods("GET")
+	api.HandleFunc("/ws", itemsHandler.Subscribe).Methods("GET")
+	api.HandleFunc("/lists", itemsHandler.Lists).Methods("GET")
+	api.HandleFunc("/lists", itemsHandler.AddList).Methods("POST")
+	api.HandleFunc("/lists/{id}", itemsHandler.SelectList).Methods("GET")
+	api.HandleFunc("/lists/{id}", itemsHandler.UpdateList).Methods("PUT")
+	api.HandleFunc("/lists/{id}", itemsHandler.DeleteList).Methods("DELETE")
+	api.HandleFunc("/lists/{id}/items", itemsHandler.ListItems).Methods("GET")
+	api.HandleFunc("/lists/{id}/workflow", itemsHandler.Workflow).Methods("GET")
+	api.HandleFunc("/lists/{id}/workflow", itemsHandler.UpdateWorkflow).Methods("PUT")
+	api.HandleFunc("/lists/{id}/board", itemsHandler.Board).Methods("GET")
+	api.HandleFunc("/lists/{id}/ws", itemsHandler.SubscribeList).Methods("GET")
+	api.HandleFunc("/lists/{id}/members", itemsHandler.Members).Methods("GET")
+	api.HandleFunc("/lists/{id}/members", itemsHandler.AddMember).Methods("POST")
+	api.HandleFunc("/lists/{id}/members/{userId}", itemsHandler.UpdateMember).Methods("PUT")
+	api.HandleFunc("/lists/{id}/members/{userId}", itemsHandler.RemoveMember).Methods("DELETE")
+	api.HandleFunc("/lists/{id}/shares", itemsHandler.ShareList).Methods("POST")
+	api.HandleFunc("/labels", itemsHandler.Labels).Methods("GET")
+	api.HandleFunc("/labels", itemsHandler.AddLabel).Methods("POST")
+	api.HandleFunc("/labels/tree", itemsHandler.LabelTree).Methods("GET")
+	api.HandleFunc("/labels/suggest", itemsHandler.SuggestLabels).Methods("GET")
+	api.HandleFunc("/labels/{id}", itemsHandler.SelectLabel).Methods("GET")
+	api.HandleFunc("/labels/{id}", itemsHandler.UpdateLabel).Methods("PUT")
+	api.HandleFunc("/labels/{id}", itemsHandler.DeleteLabel).Methods("DELETE")
+	api.HandleFunc("/labels/{id}/merge", itemsHandler.MergeLabel).Methods("POST")
+	api.HandleFunc("/shares", itemsHandler.Shares).Methods("GET")
+	api.HandleFunc("/shares/{id}", itemsHandler.RevokeShare).Methods("DELETE")
 
 	return nil
 }
 
-// Run starts the application
+// Run starts the background jobs and the application
 func (a *App) Run(addr string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.startJobs(ctx)
+
 	log.Fatal(http.ListenAndServe(addr, a.router))
 }
+
+// startJobs runs the periodic maintenance of the data in the background until the context is done
+func (a *App) startJobs(ctx context.Context) {
+	retention := a.conf.TrashRetention
+	if retention == 0 {
+		retention = 30 * 24 * time.Hour
+	}
+	go job.Every(ctx, "purge trash", time.Hour, func(ctx context.Context) error {
+		n, err := a.repo.PurgeDeletedItems(ctx, time.Now().Add(-retention))
+		if n > 0 {
+			log.Printf("Purged %d items deleted more than %s ago", n, retention)
+		}
+		return err
+	})
+
+	archiveAfter := a.conf.ArchiveAfter
+	if archiveAfter == 0 {
+		archiveAfter = 14 * 24 * time.Hour
+	}
+	go job.Every(ctx, "archive completed items", time.Hour, func(ctx context.Context) error {
+		n, err := a.repo.ArchiveCompletedItems(ctx, time.Now().Add(-archiveAfter))
+		if n > 0 {
+			log.Printf("Archived %d items done more than %s ago", n, archiveAfter)
+		}
+		return err
+	})
+
+	go job.Every(ctx, "resurface deferred items", time.Minute, func(ctx context.Context) error {
+		items, err := a.repo.ResurfaceItems(ctx, time.Now())
+		for _, i := range items {
+			topic := realtime.ListTopic(i.ListID)
+			if i.ListID == 0 {
+				topic = realtime.UserTopic(i.WorkspaceID, i.OwnerID)
+			}
+			a.hub.Publish(topic, realtime.Message{Type: realtime.TypeItemResurfaced, ListID: i.ListID, ItemID: i.ID}, i)
+		}
+		return err
+	})
+
+	go job.Every(ctx, "remove unused attachments", time.Hour, func(ctx context.Context) error {
+		n, err := a.removeUnusedBlobs(ctx, time.Now().Add(-time.Hour))
+		if n > 0 {
+			log.Printf("Removed the content of %d deleted attachments", n)
+		}
+		return err
+	})
+}
+
+// removeUnusedBlobs removes the content stored before the provided time which no attachment references anymore
+// and returns the number of removed blobs, newer content may belong to an upload which is not referenced yet
+// An upload of the same content refreshes its time, so content uploaded again while it is collected is kept
+func (a *App) removeUnusedBlobs(ctx context.Context, before time.Time) (int, error) {
+	keys, err := a.blobs.Keys(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	used, err := a.repo.UsedBlobKeys(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		if err := a.blobs.Delete(ctx, key, before); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 var a = App{}
@@ -21,114 +25,1296 @@ var testConfig = Config{
 	MysqlHost:   "127.0.0.1",
 	MysqlPort:   "3307",
 	MysqlBDName: "todolist",
+	AuthSecret:  "test-secret-which-is-long-enough-to-sign",
+	// the attachments are stored in a temporary directory set up by TestMain
+	AttachmentMaxSize:   1024,
+	RequireBlockersDone: true,
 }
 
+// token of the user the tests run as, every request is authenticated with it
+var testToken string
+var testUserID int
+
 func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "todolist-attachments")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testConfig.AttachmentsDir = dir
 
 	if err := a.Initialize(testConfig); err != nil {
 		log.Fatal(err)
 	}
 	ensureTableExists()
+	clearUsers()
+	testUserID, testToken = addUser("tester")
 	code := m.Run()
 	clearTable()
-	defer a.db.Close()
+	a.db.Close()
+	os.RemoveAll(dir)
 	os.Exit(code)
 }
 
-func TestEmptyTable(t *testing.T) {
+func TestEmptyTable(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("GET", "/items", nil)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	body := response.Body.String()
+	if strings.Trim(body, "\n") != "[]" {
+		t.Errorf("Expected an empty array. Got %s", body)
+	}
+}
+
+func TestGetNonExistentItem(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	body := response.Body.String()
+	if strings.Trim(body, "\n") != "Item not found." {
+		t.Errorf("Expected the response 'Item not found'. Got '%s'", body)
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	clearTable()
+
+	var jsonStr = []byte(testItemJSON1)
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer(jsonStr))
+	req.Header.Set("Content-Type", "application/json")
+
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	body := response.Body.String()
+	if strings.Trim(body, "\n") != `{"id":1}` {
+		t.Errorf(`Expected to receive id 1 in the format '{"id":1}'. Got '%s'`, body)
+	}
+}
+
+func TestGetProduct(t *testing.T) {
+	clearTable()
+
+	// add item to the db
+	addItems(1)
+	addComments(2, 1)
+	addLabels(1, 1)
+
+	// prepare the expected Json for comparism, the initial struct is used for readability.
+	expectedData := expectedStruct{
+		ID:      1,
+		OwnerID: testUserID,
+		Title:   "Test automatic title 1",
+		Comments: []commentStruct{
+			{ID: 1, Text: "Test automatic comment 1", UpdatedAt: "2021-05-15T13:11:50Z", CreatedAt: "2021-05-15T13:11:50Z"},
+			{ID: 2, Text: "Test automatic comment 2", UpdatedAt: "2021-05-15T13:11:50Z", CreatedAt: "2021-05-15T13:11:50Z"},
+		},
+		Labels: []labelStruct{
+			{ID: 1, Text: "Test automatic label 1"},
+		},
+		Description: "Test automatic description 1",
+		DueDate:     "2021-05-15T13:11:50Z",
+		Status:      false,
+	}
+	expectedJSON, err := json.Marshal(expectedData)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	// request the created item
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	response := executeRequest(req)
+
+	// check the response
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	body := response.Body.String()
+	expectedString := bytes.NewBuffer(expectedJSON).String()
+	if strings.Trim(body, "\n") != expectedString {
+		t.Errorf(`Expected to receive id 1 in the format '%s'. Got '%s'`, expectedString, body)
+	}
+}
+
+func TestNotFoundRoot(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestListItemsAndMove(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBuffer([]byte(`{"name":"work","color":"#00ff00"}`)))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBuffer([]byte(`{"title":"in list","listId":1}`)))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	addItems(1)
+
+	var items []expectedStruct
+	req, _ = http.NewRequest("GET", "/lists/1/items", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("Expected only the item 1 in the list. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/items/2/move", bytes.NewBuffer([]byte(`{"listId":1}`)))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("GET", "/lists/1/items", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 2 {
+		t.Errorf("Expected 2 items in the list after the move. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/items/2/move", bytes.NewBuffer([]byte(`{"listId":5}`)))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
+
+func TestUnauthenticatedRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/items", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestItemsScopedToOwner(t *testing.T) {
+	clearTable()
+	addItems(1)
+
+	_, otherToken := addUser("other")
+	req, _ := http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	req, _ = http.NewRequest("GET", "/items", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.Trim(response.Body.String(), "\n") != "[]" {
+		t.Errorf("Expected an empty array for another user. Got %s", response.Body.String())
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/me/keys", bytes.NewBufferString(`{"name":"ci","scopes":["items:read"]}`))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created struct {
+		ID  int    `json:"id"`
+		Key string `json:"key"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &created)
+
+	req, _ = http.NewRequest("GET", "/items", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(testItemJSON1))
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/me/keys/%d", created.ID), nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNoContent, response.Code)
+
+	req, _ = http.NewRequest("GET", "/items", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestSharedListRoles(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBufferString(`{"name":"team"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"shared","listId":1}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	memberID, memberToken := addUser("member")
+	req, _ = http.NewRequest("POST", "/lists/1/members", bytes.NewBufferString(`{"username":"member","role":"viewer"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// a viewer can read the items but not add new ones
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"by member","listId":1}`))
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(req).Code)
+
+	// an editor can add items
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/lists/1/members/%d", memberID), bytes.NewBufferString(`{"role":"editor"}`))
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"by member","listId":1}`))
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// only the owner manages the list
+	req, _ = http.NewRequest("DELETE", "/lists/1", nil)
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/lists/1/members/%d", memberID), nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestOwnerSeesItemsOfEditors(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBufferString(`{"name":"team"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	_, editorToken := addUser("editor")
+	req, _ = http.NewRequest("POST", "/lists/1/members", bytes.NewBufferString(`{"username":"editor","role":"editor"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"by editor","listId":1}`))
+	req.Header.Set("Authorization", "Bearer "+editorToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// the owner of the list is not one of its members
+	req, _ = http.NewRequest("GET", "/lists/1/items", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var items []expectedStruct
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 1 {
+		t.Errorf("Expected the owner to list the item of the editor. Got '%s'", response.Body.String())
+	}
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("DELETE", "/items/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/workspaces", bytes.NewBufferString(`{"slug":"acme","name":"Acme"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(testItemJSON1))
+	req.Header.Set("X-Workspace", "acme")
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	req.Header.Set("X-Workspace", "acme")
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	// the default workspace does not see the items of acme
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+
+	// users which are not members can not select the workspace
+	_, otherToken := addUser("other")
+	req, _ = http.NewRequest("GET", "/items", nil)
+	req.Header.Set("X-Workspace", "acme")
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestShareLink(t *testing.T) {
+	clearTable()
+	addItems(1)
+
+	req, _ := http.NewRequest("POST", "/items/1/shares", bytes.NewBufferString(`{}`))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created struct {
+		ID    int    `json:"id"`
+		Token string `json:"token"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &created)
+
+	// the shared content is available without authentication
+	req, _ = http.NewRequest("GET", "/shared/"+created.Token, nil)
+	anonymous := httptest.NewRecorder()
+	a.router.ServeHTTP(anonymous, req)
+	checkResponseCode(t, http.StatusOK, anonymous.Code)
+	var shared struct {
+		Item expectedStruct `json:"item"`
+	}
+	json.Unmarshal(anonymous.Body.Bytes(), &shared)
+	if shared.Item.Title != "Test automatic title 1" {
+		t.Errorf("Expected the shared item. Got '%s'", anonymous.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/shares", nil)
+	response = executeRequest(req)
+	var shares []struct {
+		AccessCount int `json:"accessCount"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &shares)
+	if len(shares) != 1 || shares[0].AccessCount != 1 {
+		t.Errorf("Expected one share link accessed once. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/shares/%d", created.ID), nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNoContent, response.Code)
+
+	req, _ = http.NewRequest("GET", "/shared/"+created.Token, nil)
+	anonymous = httptest.NewRecorder()
+	a.router.ServeHTTP(anonymous, req)
+	checkResponseCode(t, http.StatusNotFound, anonymous.Code)
+}
+
+func TestAuditLog(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/workspaces", bytes.NewBufferString(`{"slug":"acme","name":"Acme"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	mutations := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/items", testItemJSON1, http.StatusCreated},
+		{"PUT", "/items/1", `{"title":"updated title"}`, http.StatusOK},
+		{"POST", "/items/1/comments", `{"text":"looks good"}`, http.StatusCreated},
+	}
+	for _, m := range mutations {
+		req, _ = http.NewRequest(m.method, m.path, bytes.NewBufferString(m.body))
+		req.Header.Set("X-Workspace", "acme")
+		checkResponseCode(t, m.code, executeRequest(req).Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/items/1/history", nil)
+	req.Header.Set("X-Workspace", "acme")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var history []struct {
+		ID      int    `json:"id"`
+		Actor   string `json:"actor"`
+		Action  string `json:"action"`
+		Changes map[string]struct {
+			Before interface{} `json:"before"`
+			After  interface{} `json:"after"`
+		} `json:"changes"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) != 3 || history[0].Action != "item.created" || history[2].Action != "comment.added" {
+		t.Fatalf("Expected created, updated and commented entries. Got '%s'", response.Body.String())
+	}
+	if history[1].Actor != "tester" || history[1].Changes["title"].After != "updated title" {
+		t.Errorf("Expected the title change of tester. Got '%v'", history[1])
+	}
+
+	// the history pages back from the newest entries
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/items/1/history?before=%d", history[2].ID), nil)
+	req.Header.Set("X-Workspace", "acme")
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var older []struct {
+		Action string `json:"action"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &older)
+	if len(older) != 2 || older[0].Action != "item.created" {
+		t.Errorf("Expected the two entries before the comment. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/items/1", nil)
+	req.Header.Set("X-Workspace", "acme")
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+
+	// the entries outlive the item
+	req, _ = http.NewRequest("GET", "/audit?item=1&action=item.deleted", nil)
+	req.Header.Set("X-Workspace", "acme")
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) != 1 {
+		t.Errorf("Expected one deletion entry. Got '%s'", response.Body.String())
+	}
+
+	// only the admins can read the audit log of the workspace
+	req, _ = http.NewRequest("GET", "/audit", nil)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(req).Code)
+}
+
+func TestRestoreItemVersion(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(testItemJSON1))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"title":"updated title"}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("POST", "/items/1/versions/1/restore", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var restored expectedStruct
+	json.Unmarshal(response.Body.Bytes(), &restored)
+	if restored.Title != "test title 1" {
+		t.Errorf("Expected the title of the first version. Got '%s'", restored.Title)
+	}
+
+	// the restore is a new version
+	req, _ = http.NewRequest("GET", "/items/1/versions", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var versions []struct {
+		Number int            `json:"number"`
+		Item   expectedStruct `json:"item"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &versions)
+	if len(versions) != 3 || versions[1].Item.Title != "updated title" || versions[2].Item.Title != "test title 1" {
+		t.Errorf("Expected three versions. Got '%s'", response.Body.String())
+	}
+
+	// the comments come back as they were
+	req, _ = http.NewRequest("DELETE", "/items/1/comments/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"later"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1/comments/2", bytes.NewBufferString(`{"text":"changed"}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/versions/3/restore", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	restored = expectedStruct{}
+	json.Unmarshal(response.Body.Bytes(), &restored)
+	if len(restored.Comments) != 2 || restored.Comments[0].Text != "test comment 1" || restored.Comments[1].Text != "test comment 2" {
+		t.Errorf("Expected the comments of the version. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/items/1/versions/9/restore", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	clearTable()
+	addItems(2)
+
+	req, _ := http.NewRequest("DELETE", "/items/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+
+	// the deleted item is only listed in the trash
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items", nil)
+	response := executeRequest(req)
+	var items []expectedStruct
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].ID != 2 {
+		t.Errorf("Expected only the item 2. Got '%s'", response.Body.String())
+	}
+	req, _ = http.NewRequest("GET", "/trash", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].ID != 1 {
+		t.Errorf("Expected the item 1 in the trash. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/items/1/restore", nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1/versions", nil)
+	response = executeRequest(req)
+	var versions []struct {
+		Number int `json:"number"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &versions)
+	if len(versions) != 1 {
+		t.Errorf("Expected the restore to be stored as a version. Got '%s'", response.Body.String())
+	}
+
+	// items deleted before the retention period are purged
+	req, _ = http.NewRequest("DELETE", "/items/2", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	if n, err := a.repo.PurgeDeletedItems(context.Background(), time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("Expected one purged item. Got %d %v", n, err)
+	}
+	req, _ = http.NewRequest("POST", "/items/2/restore", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestArchiveCompletedItems(t *testing.T) {
+	clearTable()
+	addItems(2)
+
+	req, _ := http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"title":"done item","status":true}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	if n, err := a.repo.ArchiveCompletedItems(context.Background(), time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("Expected one archived item. Got %d %v", n, err)
+	}
+
+	// the job is recorded without an actor
+	req, _ = http.NewRequest("GET", "/items/1/history", nil)
+	response := executeRequest(req)
+	var history []struct {
+		ActorID int    `json:"actorId"`
+		Action  string `json:"action"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) == 0 || history[len(history)-1].Action != "item.archived" || history[len(history)-1].ActorID != 0 {
+		t.Errorf("Expected the archive in the history of the item. Got '%s'", response.Body.String())
+	}
+
+	var items []expectedStruct
+	req, _ = http.NewRequest("GET", "/items", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 1 || items[0].ID != 2 {
+		t.Errorf("Expected only the open item. Got '%s'", response.Body.String())
+	}
+	req, _ = http.NewRequest("GET", "/items?includeArchived=true", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 2 {
+		t.Errorf("Expected the archived item as well. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/items/unarchive", bytes.NewBufferString(`{"ids":[1,2]}`))
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if body := strings.TrimSpace(response.Body.String()); body != `{"updated":1}` {
+		t.Errorf("Expected one unarchived item. Got '%s'", body)
+	}
+}
+
+func TestRenameAndMergeLabels(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"first","labels":[{"text":"wrk"}]}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"second","labels":[{"text":"wrk"},{"text":"job"}]}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// the label is shared, renaming it applies to both items
+	req, _ = http.NewRequest("PUT", "/labels/1", bytes.NewBufferString(`{"name":"work","color":"#00ff00"}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	response := executeRequest(req)
+	var i expectedStruct
+	json.Unmarshal(response.Body.Bytes(), &i)
+	if len(i.Labels) != 1 || i.Labels[0].Text != "work" {
+		t.Errorf("Expected the renamed label. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("PUT", "/labels/2", bytes.NewBufferString(`{"name":"work"}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+
+	// the rename is recorded on the tagged items
+	req, _ = http.NewRequest("GET", "/items/2/history", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var history []struct {
+		Action string `json:"action"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) != 2 || history[1].Action != "label.updated" {
+		t.Errorf("Expected the rename in the history of the item. Got '%s'", response.Body.String())
+	}
+
+	// only the admins of the workspace merge and delete labels
+	req, _ = http.NewRequest("POST", "/labels/2/merge", bytes.NewBufferString(`{"into":1}`))
+	checkResponseCode(t, http.StatusForbidden, executeRequest(req).Code)
+	a.db.Exec("UPDATE todolist.workspaceMember SET role='admin' WHERE workspaceId=1 AND userId=?", testUserID)
+	defer a.db.Exec("UPDATE todolist.workspaceMember SET role='member' WHERE workspaceId=1 AND userId=?", testUserID)
+	req, _ = http.NewRequest("POST", "/labels/2/merge", bytes.NewBufferString(`{"into":1}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/labels", nil)
+	response = executeRequest(req)
+	var labels []struct {
+		Name  string `json:"name"`
+		Items int    `json:"items"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &labels)
+	if len(labels) != 1 || labels[0].Name != "work" || labels[0].Items != 2 {
+		t.Errorf("Expected one label of two items. Got '%s'", response.Body.String())
+	}
+}
+
+func TestHierarchicalLabels(t *testing.T) {
+	clearTable()
+
+	for _, body := range []string{
+		`{"title":"invoice","labels":[{"text":"client/acme/billing"},{"text":"client/acme/support"}]}`,
+		`{"title":"ticket","labels":[{"text":"client/acme/support"}]}`,
+		`{"title":"call","labels":[{"text":"client/beta"}]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(body))
+		checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/items?q=label:client/acme/*", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var items []expectedStruct
+	json.Unmarshal(response.Body.Bytes(), &items)
+	if len(items) != 2 {
+		t.Errorf("Expected the two acme items. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/labels/tree", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var tree []struct {
+		Path      string `json:"path"`
+		OpenItems int    `json:"openItems"`
+		Children  []struct {
+			Path      string `json:"path"`
+			OpenItems int    `json:"openItems"`
+		} `json:"children"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &tree)
+	if len(tree) != 1 || tree[0].OpenItems != 3 || len(tree[0].Children) != 2 || tree[0].Children[0].Path != "client/acme" || tree[0].Children[0].OpenItems != 2 {
+		t.Errorf("Expected client with 3 open items and client/acme with 2. Got '%s'", response.Body.String())
+	}
+
+	// the labels are shared by the workspace, the items of the others are not counted
+	_, otherToken := addUser("other")
+	req, _ = http.NewRequest("GET", "/labels/tree", nil)
+	req.Header.Set("Authorization", "Bearer "+otherToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	tree = nil
+	json.Unmarshal(response.Body.Bytes(), &tree)
+	if len(tree) != 1 || tree[0].OpenItems != 0 {
+		t.Errorf("Expected client without open items for another user. Got '%s'", response.Body.String())
+	}
+}
+
+func TestSuggestLabels(t *testing.T) {
+	clearTable()
+
+	for _, labels := range []string{`"work"`, `"work","workshop"`, `"work","home"`} {
+		req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"item","labels":[{"text":`+strings.Replace(labels, `,`, `},{"text":`, -1)+`}]}`))
+		checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/labels/suggest?prefix=wo", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var labels []struct {
+		Name string `json:"name"`
+		Uses int    `json:"uses"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &labels)
+	if len(labels) != 2 || labels[0].Name != "work" || labels[0].Uses != 3 || labels[1].Name != "workshop" {
+		t.Errorf("Expected work before workshop. Got '%s'", response.Body.String())
+	}
+}
+
+func TestCommentCRUD(t *testing.T) {
+	clearTable()
+	addItems(1)
+
+	req, _ := http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"first"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"second"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"`+strings.Repeat("a", 501)+`"}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("PUT", "/items/1/comments/1", bytes.NewBufferString(`{"text":"first, edited"}`))
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var c commentStruct
+	json.Unmarshal(response.Body.Bytes(), &c)
+	if c.Text != "first, edited" || c.CreatedAt == "" || c.UpdatedAt == "" {
+		t.Errorf("Expected the edited comment with its timestamps. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/items/1/comments/2", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("DELETE", "/items/1/comments/2", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1/comments", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var comments []commentStruct
+	json.Unmarshal(response.Body.Bytes(), &comments)
+	if len(comments) != 1 || comments[0].Text != "first, edited" {
+		t.Errorf("Expected only the edited comment. Got '%s'", response.Body.String())
+	}
+}
+
+func TestCommentThreadsAndEdits(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBufferString(`{"name":"team"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"shared","listId":1}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	_, memberToken := addUser("member")
+	req, _ = http.NewRequest("POST", "/lists/1/members", bytes.NewBufferString(`{"username":"member","role":"commenter"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"question"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"answer","parentId":1}`))
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"lost","parentId":9}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+
+	// only the author edits a comment
+	req, _ = http.NewRequest("PUT", "/items/1/comments/2", bytes.NewBufferString(`{"text":"better answer"}`))
+	checkResponseCode(t, http.StatusForbidden, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1/comments/2", bytes.NewBufferString(`{"text":"better answer"}`))
+	req.Header.Set("Authorization", "Bearer "+memberToken)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1/comments", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var threads []struct {
+		Text    string `json:"text"`
+		Author  string `json:"author"`
+		Replies []struct {
+			Text   string `json:"text"`
+			Author string `json:"author"`
+			Edited bool   `json:"edited"`
+		} `json:"replies"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &threads)
+	if len(threads) != 1 || len(threads[0].Replies) != 1 || threads[0].Replies[0].Author != "member" || !threads[0].Replies[0].Edited || threads[0].Replies[0].Text != "better answer" {
+		t.Errorf("Expected the edited answer nested under the question. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/items/1/comments/2/edits", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var edits []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &edits)
+	if len(edits) != 1 || edits[0].Text != "answer" {
+		t.Errorf("Expected the previous text of the answer. Got '%s'", response.Body.String())
+	}
+
+	// deleting a comment removes its replies
+	req, _ = http.NewRequest("DELETE", "/items/1/comments/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1/comments", nil)
+	response = executeRequest(req)
+	if strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("Expected no comments left. Got '%s'", response.Body.String())
+	}
+}
+
+func TestMentions(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBufferString(`{"name":"team"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"shared","listId":1}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	_, aliceToken := addUser("alice")
+	_, bobToken := addUser("bob")
+	req, _ = http.NewRequest("POST", "/lists/1/members", bytes.NewBufferString(`{"username":"alice","role":"commenter"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// bob can not see the item so he is not mentioned
+	req, _ = http.NewRequest("POST", "/items/1/comments", bytes.NewBufferString(`{"text":"@alice please review, cc @bob"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/me/mentions", nil)
+	req.Header.Set("Authorization", "Bearer "+bobToken)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("Expected no mentions of bob. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/me/mentions?unread=true", nil)
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var mentions []struct {
+		ID        int    `json:"id"`
+		ItemID    int    `json:"itemId"`
+		CommentID int    `json:"commentId"`
+		Text      string `json:"text"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &mentions)
+	if len(mentions) != 1 || mentions[0].ItemID != 1 || mentions[0].CommentID != 1 || mentions[0].Text != "@alice please review, cc @bob" {
+		t.Errorf("Expected alice to be mentioned in the comment. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/me/mentions/read", bytes.NewBufferString(fmt.Sprintf(`{"ids":[%d]}`, mentions[0].ID)))
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.TrimSpace(response.Body.String()) != `{"updated":1}` {
+		t.Errorf("Expected one mention to be read. Got '%s'", response.Body.String())
+	}
+	req, _ = http.NewRequest("GET", "/me/mentions?unread=true", nil)
+	req.Header.Set("Authorization", "Bearer "+aliceToken)
+	response = executeRequest(req)
+	if strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("Expected no unread mentions. Got '%s'", response.Body.String())
+	}
+}
+
+func TestRenderDescription(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"plan","description":"- [x] **draft**\n- [ ] review <script>alert(1)</script>"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.Contains(response.Body.String(), "descriptionHtml") {
+		t.Errorf("Expected no html unless requested. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/items/1?render=html", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	html, _ := m["descriptionHtml"].(string)
+	if !strings.Contains(html, `<input checked="" disabled="" type="checkbox"> <strong>draft</strong>`) || strings.Contains(html, "<script>") {
+		t.Errorf("Expected the sanitized html with a checked task. Got '%s'", html)
+	}
+
+	req, _ = http.NewRequest("GET", "/items/1?render=pdf", nil)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+}
+
+func TestChecklist(t *testing.T) {
+	clearTable()
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"release","checklist":[{"text":"tag"},{"text":"build"}]}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/checklist", bytes.NewBufferString(`{"text":"announce"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("POST", "/items/1/checklist/1/toggle", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	if strings.TrimSpace(response.Body.String()) != `{"id":1,"text":"tag","checked":true}` {
+		t.Errorf("Expected the checked entry. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("PUT", "/items/1/checklist/order", bytes.NewBufferString(`{"ids":[2,1]}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1/checklist/order", bytes.NewBufferString(`{"ids":[2,3,1]}`))
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var i struct {
+		Checklist []struct {
+			ID int `json:"id"`
+		} `json:"checklist"`
+		Progress struct {
+			Done  int `json:"done"`
+			Total int `json:"total"`
+		} `json:"progress"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &i)
+	if len(i.Checklist) != 3 || i.Checklist[0].ID != 2 || i.Checklist[2].ID != 1 || i.Progress.Done != 1 || i.Progress.Total != 3 {
+		t.Errorf("Expected the reordered checklist with one of three entries done. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", "/items/1/checklist/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/checklist/1/toggle", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestAttachments(t *testing.T) {
+	clearTable()
+	addItems(1)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 100)...)
+	upload := func(name string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", name)
+		fw.Write(content)
+		mw.Close()
+		req, _ := http.NewRequest("POST", "/items/1/attachments", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return executeRequest(req)
+	}
+
+	response := upload("screen.png", png)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var created struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+		Size        int    `json:"size"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &created)
+	if created.Name != "screen.png" || created.ContentType != "image/png" || created.Size != len(png) {
+		t.Errorf("Expected the sniffed png attachment. Got '%s'", response.Body.String())
+	}
+	checkResponseCode(t, http.StatusCreated, upload("copy.png", png).Code)
+	checkResponseCode(t, http.StatusRequestEntityTooLarge, upload("large.bin", make([]byte, 2048)).Code)
+
+	req, _ := http.NewRequest("GET", "/items/1/attachments/1", nil)
+	req.Header.Set("Range", "bytes=0-3")
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusPartialContent, response.Code)
+	if response.Body.String() != "\x89PNG" {
+		t.Errorf("Expected the first 4 bytes of the file. Got '%q'", response.Body.String())
+	}
+
+	// the content shared by both attachments is removed with the last of them
+	req, _ = http.NewRequest("DELETE", "/items/1/attachments/1", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	if n, err := a.removeUnusedBlobs(context.Background(), time.Now().Add(time.Minute)); err != nil || n != 0 {
+		t.Errorf("Expected the content to be kept. Got %d removed, %v", n, err)
+	}
+	req, _ = http.NewRequest("DELETE", "/items/1/attachments/2", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	if n, err := a.removeUnusedBlobs(context.Background(), time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("Expected the content to be removed. Got %d removed, %v", n, err)
+	}
+
+	req, _ = http.NewRequest("GET", "/items/1/attachments", nil)
+	response = executeRequest(req)
+	if strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("Expected no attachments left. Got '%s'", response.Body.String())
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	clearTable()
+	addItems(3)
+
+	req, _ := http.NewRequest("POST", "/items/1/dependencies", bytes.NewBufferString(`{"blockedBy":2}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/2/dependencies", bytes.NewBufferString(`{"blockedBy":3}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/3/dependencies", bytes.NewBufferString(`{"blockedBy":1}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/dependencies", bytes.NewBufferString(`{"blockedBy":1}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/dependencies", bytes.NewBufferString(`{"blockedBy":99}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/2/dependencies", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var d struct {
+		BlockedBy []struct {
+			ID int `json:"id"`
+		} `json:"blockedBy"`
+		Blocks []struct {
+			ID int `json:"id"`
+		} `json:"blocks"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &d)
+	if len(d.BlockedBy) != 1 || d.BlockedBy[0].ID != 3 || len(d.Blocks) != 1 || d.Blocks[0].ID != 1 {
+		t.Errorf("Expected item 2 blocked by 3 and blocking 1. Got '%s'", response.Body.String())
+	}
+
+	// the tests run with RequireBlockersDone
+	req, _ = http.NewRequest("PUT", "/items/2", bytes.NewBufferString(`{"title":"title","status":true}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/3", bytes.NewBufferString(`{"title":"title","status":true}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var items []struct {
+		ID      int  `json:"id"`
+		Blocked bool `json:"blocked"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &items)
+	for _, i := range items {
+		if i.Blocked != (i.ID == 1) {
+			t.Errorf("Expected only item 1 to be blocked. Got '%s'", response.Body.String())
+		}
+	}
+
+	req, _ = http.NewRequest("DELETE", "/items/1/dependencies/2", nil)
+	checkResponseCode(t, http.StatusNoContent, executeRequest(req).Code)
+	req, _ = http.NewRequest("DELETE", "/items/1/dependencies/2", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+}
+
+func TestWorkflowBoard(t *testing.T) {
 	clearTable()
 
-	req, _ := http.NewRequest("GET", "/items", nil)
+	req, _ := http.NewRequest("POST", "/lists", bytes.NewBufferString(`{"name":"team"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"design","listId":1}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"setup","listId":1,"status":true}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+
+	// the default workflow does not skip the steps
+	req, _ = http.NewRequest("PUT", "/items/1/state", bytes.NewBufferString(`{"state":"Done"}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1/state", bytes.NewBufferString(`{"state":"Nowhere"}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/items/1/state", bytes.NewBufferString(`{"state":"In progress"}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/lists/1/board", nil)
 	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var columns []struct {
+		Name  string `json:"name"`
+		Items []struct {
+			ID int `json:"id"`
+		} `json:"items"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &columns)
+	if len(columns) != 4 || columns[0].Name != "Backlog" || len(columns[0].Items) != 0 ||
+		len(columns[1].Items) != 1 || columns[1].Items[0].ID != 1 || len(columns[3].Items) != 1 || columns[3].Items[0].ID != 2 {
+		t.Errorf("Expected the items in progress and done. Got '%s'", response.Body.String())
+	}
+
+	req, _ = http.NewRequest("PUT", "/lists/1/workflow", bytes.NewBufferString(`{"states":[{"name":"Todo"},{"name":"Done","done":true}]}`))
+	checkResponseCode(t, http.StatusConflict, executeRequest(req).Code)
+	req, _ = http.NewRequest("PUT", "/lists/1/workflow", bytes.NewBufferString(`{"states":[{"name":"In progress"},{"name":"Done","done":true}]}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
 
+	// a status alone puts the item in the first state matching it
+	req, _ = http.NewRequest("PUT", "/items/1", bytes.NewBufferString(`{"title":"design","status":true}`))
+	response = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, response.Code)
+	var i struct {
+		Status bool   `json:"status"`
+		State  string `json:"state"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &i)
+	if !i.Status || i.State != "Done" {
+		t.Errorf("Expected the item to be done. Got '%s'", response.Body.String())
+	}
 
-	body := response.Body.String()
-	if strings.Trim(body, "\n") != "[]" {
-		t.Errorf("Expected an empty array. Got %s", body)
+	// the items of a state which is no longer done are reopened and it is kept in their history
+	req, _ = http.NewRequest("PUT", "/lists/1/workflow", bytes.NewBufferString(`{"states":[{"name":"In progress"},{"name":"Done"},{"name":"Shipped","done":true}]}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items/1/history", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var history []struct {
+		Action  string `json:"action"`
+		Changes map[string]struct {
+			Before interface{} `json:"before"`
+			After  interface{} `json:"after"`
+		} `json:"changes"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &history)
+	if len(history) == 0 || history[len(history)-1].Action != "item.updated" ||
+		history[len(history)-1].Changes["status"].Before != true || history[len(history)-1].Changes["status"].After != false {
+		t.Errorf("Expected the item to be reopened in its history. Got '%s'", response.Body.String())
 	}
 }
 
-func TestGetNonExistentItem(t *testing.T) {
+func TestPositions(t *testing.T) {
 	clearTable()
+	addItems(3)
 
-	req, _ := http.NewRequest("GET", "/items/1", nil)
-	response := executeRequest(req)
+	order := func() []int {
+		req, _ := http.NewRequest("GET", "/items?sort=position", nil)
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var items []struct {
+			ID int `json:"id"`
+		}
+		json.Unmarshal(response.Body.Bytes(), &items)
+		ids := []int{}
+		for _, i := range items {
+			ids = append(ids, i.ID)
+		}
+		return ids
+	}
 
-	checkResponseCode(t, http.StatusNotFound, response.Code)
+	// the items added without a rank are ranked on the first move
+	req, _ := http.NewRequest("POST", "/items/3/position", bytes.NewBufferString(`{"before":1}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	if ids := order(); fmt.Sprint(ids) != "[3 1 2]" {
+		t.Errorf("Expected the items in the order [3 1 2]. Got '%v'", ids)
+	}
+	req, _ = http.NewRequest("POST", "/items/3/position", bytes.NewBufferString(`{"after":1}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/position", bytes.NewBufferString(`{"after":2}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	if ids := order(); fmt.Sprint(ids) != "[3 2 1]" {
+		t.Errorf("Expected the items in the order [3 2 1]. Got '%v'", ids)
+	}
 
-	body := response.Body.String()
-	if strings.Trim(body, "\n") != "Item not found." {
-		t.Errorf("Expected the response 'Item not found'. Got '%s'", body)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"last"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	if ids := order(); fmt.Sprint(ids) != "[3 2 1 4]" {
+		t.Errorf("Expected the new item at the end. Got '%v'", ids)
 	}
+
+	req, _ = http.NewRequest("POST", "/items/1/position", bytes.NewBufferString(`{"before":99}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/position", bytes.NewBufferString(`{"before":2,"after":3}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/items?sort=title", nil)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
 }
 
-func TestCreateProduct(t *testing.T) {
+func TestDeferredItems(t *testing.T) {
 	clearTable()
 
-	var jsonStr = []byte(testItemJSON1)
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"later","startDate":"2999-01-01T00:00:00Z"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"now"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
 
-	req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer(jsonStr))
-	req.Header.Set("Content-Type", "application/json")
+	count := func(query string) int {
+		req, _ := http.NewRequest("GET", "/items"+query, nil)
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+		var items []expectedStruct
+		json.Unmarshal(response.Body.Bytes(), &items)
+		return len(items)
+	}
+	if n := count(""); n != 1 {
+		t.Errorf("Expected the item which has not started to be left out. Got %d items", n)
+	}
+	if n := count("?includeDeferred=true"); n != 2 {
+		t.Errorf("Expected both items with includeDeferred. Got %d items", n)
+	}
 
-	response := executeRequest(req)
-	checkResponseCode(t, http.StatusCreated, response.Code)
+	req, _ = http.NewRequest("POST", "/items/2/snooze", bytes.NewBufferString(`{"until":"2000-01-01T00:00:00Z"}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/2/snooze", bytes.NewBufferString(`{"until":"2999-01-01T00:00:00Z"}`))
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	if n := count(""); n != 0 {
+		t.Errorf("Expected the snoozed item to be left out. Got %d items", n)
+	}
 
-	body := response.Body.String()
-	if strings.Trim(body, "\n") != `{"id":1}` {
-		t.Errorf(`Expected to receive id 1 in the format '{"id":1}'. Got '%s'`, body)
+	// the snooze ends, the job resurfaces the item
+	a.db.Exec("UPDATE todolist.item SET snoozed='2020-01-01 00:00:00' WHERE id=2")
+	items, err := a.repo.ResurfaceItems(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != 2 || items[0].SnoozedUntil != nil {
+		t.Errorf("Expected the snoozed item to be resurfaced. Got '%v'", items)
+	}
+	if n := count(""); n != 1 {
+		t.Errorf("Expected the resurfaced item to be listed. Got %d items", n)
 	}
 }
 
-func TestGetProduct(t *testing.T) {
+func TestTimeTracking(t *testing.T) {
 	clearTable()
 
-	// add item to the db
-	addItems(1)
-	addComments(2, 1)
-	addLabels(1, 1)
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBufferString(`{"title":"review","estimate":3600}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
 
-	// prepare the expected Json for comparism, the initial struct is used for readability.
-	expectedData := expectedStruct{
-		ID:    1,
-		Title: "Test automatic title 1",
-		Comments: []commentStruct{
-			{ID: 1, Text: "Test automatic comment 1"},
-			{ID: 2, Text: "Test automatic comment 2"},
-		},
-		Labels: []labelStruct{
-			{ID: 1, Text: "Test automatic label 1"},
-		},
-		Description: "Test automatic description 1",
-		DueDate:     "2021-05-15T13:11:50Z",
-		Status:      false,
-	}
-	expectedJSON, err := json.Marshal(expectedData)
-	if err != nil {
-		log.Fatal(err.Error())
-	}
+	req, _ = http.NewRequest("POST", "/items/1/time", bytes.NewBufferString(`{"duration":0}`))
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/time", bytes.NewBufferString(`{"duration":1800,"note":"first pass"}`))
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
 
-	// request the created item
-	req, _ := http.NewRequest("GET", "/items/1", nil)
+	req, _ = http.NewRequest("POST", "/items/1/time/start", nil)
+	checkResponseCode(t, http.StatusCreated, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/time/stop", nil)
+	checkResponseCode(t, http.StatusOK, executeRequest(req).Code)
+	req, _ = http.NewRequest("POST", "/items/1/time/stop", nil)
+	checkResponseCode(t, http.StatusNotFound, executeRequest(req).Code)
+
+	req, _ = http.NewRequest("GET", "/items/1/time", nil)
 	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var tracking struct {
+		Estimate int
+		Tracked  int
+		Entries  []map[string]interface{}
+	}
+	json.Unmarshal(response.Body.Bytes(), &tracking)
+	if tracking.Estimate != 3600 || tracking.Tracked < 1800 || len(tracking.Entries) != 2 {
+		t.Errorf("Expected the estimate, at least 1800 seconds tracked and 2 entries. Got '%s'", response.Body.String())
+	}
 
-	// check the response
+	// the entries are recorded in the history of the item
+	req, _ = http.NewRequest("GET", "/items/1/history", nil)
+	response = executeRequest(req)
 	checkResponseCode(t, http.StatusOK, response.Code)
+	for _, action := range []string{"time.added", "time.started", "time.stopped"} {
+		if !strings.Contains(response.Body.String(), `"`+action+`"`) {
+			t.Errorf("Expected %s in the history of the item. Got '%s'", action, response.Body.String())
+		}
+	}
 
-	body := response.Body.String()
-	expectedString := bytes.NewBuffer(expectedJSON).String()
-	if strings.Trim(body, "\n") != expectedString {
-		t.Errorf(`Expected to receive id 1 in the format '%s'. Got '%s'`, expectedString, body)
+	req, _ = http.NewRequest("GET", "/reports/time", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var report struct {
+		Labels []struct {
+			Items     int
+			Tracked   int
+			Estimated int
+		}
+	}
+	json.Unmarshal(response.Body.Bytes(), &report)
+	if len(report.Labels) != 1 || report.Labels[0].Items != 1 || report.Labels[0].Estimated != 3600 {
+		t.Errorf("Expected one row for the unlabelled item. Got '%s'", response.Body.String())
 	}
+
+	req, _ = http.NewRequest("GET", "/reports/time?from=yesterday", nil)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
+	req, _ = http.NewRequest("GET", "/reports/time?from=2021-05-15&to=2021-05-01", nil)
+	checkResponseCode(t, http.StatusBadRequest, executeRequest(req).Code)
 }
 
-func TestNotFoundRoot(t *testing.T) {
+func TestSubscribeReceivesCreatedItem(t *testing.T) {
 	clearTable()
 
-	req, _ := http.NewRequest("GET", "/", nil)
+	server := httptest.NewServer(a.router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?access_token="+testToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	req, _ := http.NewRequest("POST", "/items", bytes.NewBuffer([]byte(testItemJSON1)))
 	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
 
-	checkResponseCode(t, http.StatusNotFound, response.Code)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var m struct {
+		Type   string `json:"type"`
+		ItemID int    `json:"itemId"`
+	}
+	if err := conn.ReadJSON(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != "item.created" || m.ItemID != 1 {
+		t.Errorf("Expected an item.created message for item 1. Got '%s' for item %d", m.Type, m.ItemID)
+	}
 }
 
 func addItems(count int) {
@@ -138,7 +1324,7 @@ func addItems(count int) {
 		log.Fatal(err)
 	}
 	for i := 1; i <= count; i++ {
-		_, err := a.db.Exec("INSERT INTO item(title, description, due) VALUES(?, ?, ?)", fmt.Sprintf("Test automatic title %d", i), fmt.Sprintf("Test automatic description %d", i), due)
+		_, err := a.db.Exec("INSERT INTO item(workspaceId, ownerId, title, description, due) VALUES(1, ?, ?, ?, ?)", testUserID, fmt.Sprintf("Test automatic title %d", i), fmt.Sprintf("Test automatic description %d", i), due)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -147,7 +1333,7 @@ func addItems(count int) {
 
 func addComments(count int, itemID int) {
 	for i := 1; i <= count; i++ {
-		_, err := a.db.Exec("INSERT INTO comment(itemId, comment) VALUES(?, ?)", itemID, fmt.Sprintf("Test automatic comment %d", i))
+		_, err := a.db.Exec("INSERT INTO comment(workspaceId, itemId, comment, created, updated) VALUES(1, ?, ?, '2021-05-15 13:11:50', '2021-05-15 13:11:50')", itemID, fmt.Sprintf("Test automatic comment %d", i))
 		if err != nil {
 			log.Fatal(err.Error())
 		}
@@ -156,11 +1342,39 @@ func addComments(count int, itemID int) {
 
 func addLabels(count int, itemID int) {
 	for i := 1; i <= count; i++ {
-		_, err := a.db.Exec("INSERT INTO label(itemId, label) VALUES(?, ?)", itemID, fmt.Sprintf("Test automatic label %d", i))
+		res, err := a.db.Exec("INSERT INTO label(workspaceId, name) VALUES(1, ?)", fmt.Sprintf("Test automatic label %d", i))
 		if err != nil {
 			log.Fatal(err.Error())
 		}
+		labelID, _ := res.LastInsertId()
+		_, err = a.db.Exec("INSERT INTO itemLabel(itemId, labelId, position) VALUES(?, ?, ?)", itemID, labelID, i)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+}
+
+// addUser registers a user through the api and returns its id and session token
+func addUser(username string) (int, string) {
+	body := fmt.Sprintf(`{"username":"%s","email":"%s@example.com","password":"password123"}`, username, username)
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(body))
+	response := executeRequest(req)
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		log.Fatal(response.Body.String())
 	}
+
+	req, _ = http.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	response = executeRequest(req)
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &login); err != nil {
+		log.Fatal(response.Body.String())
+	}
+	return created.ID, login.Token
 }
 
 func checkResponseCode(t *testing.T, expected, actual int) {
@@ -170,6 +1384,9 @@ func checkResponseCode(t *testing.T, expected, actual int) {
 }
 
 func executeRequest(req *http.Request) *httptest.ResponseRecorder {
+	if req.Header.Get("Authorization") == "" && testToken != "" {
+		req.Header.Set("Authorization", "Bearer "+testToken)
+	}
 	rr := httptest.NewRecorder()
 	a.router.ServeHTTP(rr, req)
 
@@ -177,17 +1394,35 @@ func executeRequest(req *http.Request) *httptest.ResponseRecorder {
 }
 
 func ensureTableExists() {
-	if _, err := a.db.Exec(tableItemCreationQuery); err != nil {
-		fmt.Println("fail to execute")
-		log.Fatal(err)
-	}
-	if _, err := a.db.Exec(tableCommentCreationQuery); err != nil {
-		fmt.Println("fail to execute")
-		log.Fatal(err)
-	}
-	if _, err := a.db.Exec(tableLabelCreationQuery); err != nil {
-		fmt.Println("fail to execute")
-		log.Fatal(err)
+	// the queries are executed in order, tables are created after the ones they reference
+	for _, query := range []string{
+		tableUserCreationQuery,
+		tableWorkspaceCreationQuery,
+		workspaceDefaultRowQuery,
+		tableWorkspaceMemberCreationQuery,
+		tableAPIKeyCreationQuery,
+		tableListCreationQuery,
+		tableListMemberCreationQuery,
+		tableWorkflowStateCreationQuery,
+		tableWorkflowTransitionCreationQuery,
+		tableItemCreationQuery,
+		tableCommentCreationQuery,
+		tableCommentEditCreationQuery,
+		tableChecklistCreationQuery,
+		tableAttachmentCreationQuery,
+		tableTimeEntryCreationQuery,
+		tableItemDependencyCreationQuery,
+		tableMentionCreationQuery,
+		tableLabelCreationQuery,
+		tableItemLabelCreationQuery,
+		tableShareCreationQuery,
+		tableAuditCreationQuery,
+		tableItemVersionCreationQuery,
+	} {
+		if _, err := a.db.Exec(query); err != nil {
+			fmt.Println("fail to execute")
+			log.Fatal(err)
+		}
 	}
 }
 
@@ -196,12 +1431,36 @@ func clearTable() {
 	a.db.Exec("ALTER TABLE todolist.item AUTO_INCREMENT = 1")
 	a.db.Exec("DELETE FROM todolist.comment")
 	a.db.Exec("ALTER TABLE todolist.comment AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.attachment")
+	a.db.Exec("ALTER TABLE todolist.attachment AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.checklist")
+	a.db.Exec("ALTER TABLE todolist.checklist AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.itemDependency")
+	a.db.Exec("DELETE FROM todolist.timeEntry")
+	a.db.Exec("ALTER TABLE todolist.timeEntry AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.itemLabel")
 	a.db.Exec("DELETE FROM todolist.label")
 	a.db.Exec("ALTER TABLE todolist.label AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.listMember")
+	a.db.Exec("DELETE FROM todolist.list")
+	a.db.Exec("ALTER TABLE todolist.list AUTO_INCREMENT = 1")
+	a.db.Exec("DELETE FROM todolist.share")
+	a.db.Exec("DELETE FROM todolist.audit")
+	a.db.Exec("DELETE FROM todolist.itemVersion")
+	a.db.Exec("DELETE FROM todolist.apiKey")
+	a.db.Exec("DELETE FROM todolist.workspace WHERE id<>1")
+	a.db.Exec("DELETE FROM todolist.user WHERE id<>?", testUserID)
+}
+
+func clearUsers() {
+	a.db.Exec("DELETE FROM todolist.user")
+	a.db.Exec("ALTER TABLE todolist.user AUTO_INCREMENT = 1")
 }
 
 type expectedStruct struct {
 	ID          int             `json:"id"`
+	OwnerID     int             `json:"ownerId"`
+	ListID      int             `json:"listId"`
 	Title       string          `json:"title"`
 	Description string          `json:"description"`
 	Labels      []labelStruct   `json:"labels"`
@@ -211,8 +1470,10 @@ type expectedStruct struct {
 }
 
 type commentStruct struct {
-	ID   int    `json:"id"`
-	Text string `json:"text"`
+	ID        int    `json:"id"`
+	Text      string `json:"text"`
+	UpdatedAt string `json:"updatedAt"`
+	CreatedAt string `json:"createdAt"`
 }
 
 type labelStruct struct {
@@ -228,39 +1489,411 @@ const testItemJSON1 = `{
 	"labels":[{"text":"test label 1"},{"text":"test label 2"}]
 	}`
 
+const tableUserCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.user (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	username VARCHAR(30) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	email VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	password VARCHAR(60) NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE (username),
+    UNIQUE (email)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableWorkspaceCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.workspace (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	slug VARCHAR(31) NOT NULL,
+	name VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE (slug)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const workspaceDefaultRowQuery = `INSERT IGNORE INTO todolist.workspace (id, slug, name) VALUES (1, 'default', 'Default');`
+
+const tableWorkspaceMemberCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.workspaceMember (
+	workspaceId INT(6) NOT NULL,
+	userId INT(6) NOT NULL,
+	role ENUM('member', 'admin') NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspaceId, userId),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (userId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    INDEX (userId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableAPIKeyCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.apiKey (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	userId INT(6) NOT NULL,
+	workspaceId INT(6) NOT NULL,
+	name VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	prefix CHAR(8) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	scopes VARCHAR(255) NOT NULL,
+	lastUsed DATETIME,
+	revoked DATETIME,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (userId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    UNIQUE (prefix)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableListCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.list (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	ownerId INT(6) NOT NULL,
+	name VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	description VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	color VARCHAR(7) NOT NULL DEFAULT '',
+	archived BOOLEAN NOT NULL DEFAULT false,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (ownerId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    INDEX (workspaceId, ownerId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableListMemberCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.listMember (
+	listId INT(6) NOT NULL,
+	userId INT(6) NOT NULL,
+	role ENUM('viewer', 'commenter', 'editor') NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (listId, userId),
+    FOREIGN KEY (listId)
+        REFERENCES list(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (userId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    INDEX (userId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableWorkflowStateCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.workflowState (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	listId INT(6) NOT NULL,
+	name VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	position INT(6) NOT NULL,
+	done BOOLEAN NOT NULL DEFAULT false,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (listId)
+        REFERENCES list(id)
+        ON DELETE CASCADE,
+    UNIQUE (listId, name)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableWorkflowTransitionCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.workflowTransition (
+	listId INT(6) NOT NULL,
+	fromId INT(6) NOT NULL,
+	toId INT(6) NOT NULL,
+    PRIMARY KEY (fromId, toId),
+    FOREIGN KEY (listId)
+        REFERENCES list(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (fromId)
+        REFERENCES workflowState(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (toId)
+        REFERENCES workflowState(id)
+        ON DELETE CASCADE,
+    INDEX (listId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
 const tableItemCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.item (
 	id INT(6) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	ownerId INT(6) NOT NULL,
+	listId INT(6),
 	title VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
 	description VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci,
 	status BOOLEAN NOT NULL DEFAULT false,
+	stateId INT(6),
+	position VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '',
 	due DATETIME,
+	start DATETIME,
+	snoozed DATETIME,
+	deferred BOOLEAN NOT NULL DEFAULT false,
+	estimate INT NOT NULL DEFAULT 0,
+	completed DATETIME,
+	archived DATETIME,
+	deleted DATETIME,
 	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    PRIMARY KEY (id)
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (ownerId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (listId)
+        REFERENCES list(id)
+        ON DELETE SET NULL,
+    FOREIGN KEY (stateId)
+        REFERENCES workflowState(id)
+        ON DELETE SET NULL,
+    INDEX (workspaceId, ownerId),
+    INDEX (listId, position),
+    INDEX (deleted),
+    INDEX (completed),
+    INDEX (deferred)
 )ENGINE=InnoDB DEFAULT CHARSET=utf8;`
 
 const tableCommentCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.comment (
     id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
     itemId INT(6) NOT NULL,
+    authorId INT(6),
+    parentId INT(6),
     comment VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    edited BOOLEAN NOT NULL DEFAULT false,
     created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
     updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
     FOREIGN KEY (itemId) 
         REFERENCES item(id) 
         ON DELETE CASCADE,
+    FOREIGN KEY (authorId)
+        REFERENCES user(id)
+        ON DELETE SET NULL,
+    FOREIGN KEY (parentId)
+        REFERENCES comment(id)
+        ON DELETE CASCADE,
     INDEX (itemId)
 )ENGINE=InnoDB DEFAULT CHARSET=utf8;`
 
-const tableLabelCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.label (
+const tableCommentEditCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.commentEdit (
+    id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
+    commentId INT(6) NOT NULL,
+    comment VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (commentId)
+        REFERENCES comment(id)
+        ON DELETE CASCADE,
+    INDEX (commentId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableChecklistCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.checklist (
+    id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
+    itemId INT(6) NOT NULL,
+    text VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    checked BOOLEAN NOT NULL DEFAULT false,
+    position INT NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    INDEX (itemId, position)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableAttachmentCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.attachment (
+    id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
+    itemId INT(6) NOT NULL,
+    uploaderId INT(6) NOT NULL,
+    name VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    contentType VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    blobKey CHAR(64) NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    INDEX (itemId),
+    INDEX (blobKey)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableTimeEntryCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.timeEntry (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	itemId INT(6) NOT NULL,
+	userId INT(6) NOT NULL,
+	started DATETIME NOT NULL,
+	ended DATETIME,
+	duration INT,
+	note VARCHAR(200) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (userId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    INDEX (itemId),
+    INDEX (userId, ended),
+    INDEX (workspaceId, started)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableItemDependencyCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.itemDependency (
+    workspaceId INT(6) NOT NULL,
+    itemId INT(6) NOT NULL,
+    blockerId INT(6) NOT NULL,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (itemId, blockerId),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (blockerId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    INDEX (blockerId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableMentionCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.mention (
     id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
+    userId INT(6) NOT NULL,
     itemId INT(6) NOT NULL,
-    label VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    commentId INT(6),
+    authorId INT(6) NOT NULL,
+    readAt DATETIME,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (userId)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (commentId)
+        REFERENCES comment(id)
+        ON DELETE CASCADE,
+    INDEX (userId, workspaceId, readAt)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableLabelCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.label (
+    id INT(6) NOT NULL AUTO_INCREMENT,
+    workspaceId INT(6) NOT NULL,
+    name VARCHAR(100) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    description VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+    uses INT NOT NULL DEFAULT 0,
+    lastUsed DATETIME,
     created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
     updated DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    UNIQUE (workspaceId, name)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableItemLabelCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.itemLabel (
+    itemId INT(6) NOT NULL,
+    labelId INT(6) NOT NULL,
+    position INT(6) NOT NULL DEFAULT 0,
+    created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (itemId, labelId),
     FOREIGN KEY (itemId)
         REFERENCES item(id)
         ON DELETE CASCADE,
-    INDEX (itemId)
+    FOREIGN KEY (labelId)
+        REFERENCES label(id)
+        ON DELETE CASCADE,
+    INDEX (labelId)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableShareCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.share (
+	id INT(6) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	createdBy INT(6) NOT NULL,
+	itemId INT(6),
+	listId INT(6),
+	hash CHAR(64) NOT NULL,
+	expires DATETIME NOT NULL,
+	revoked DATETIME,
+	accessCount INT NOT NULL DEFAULT 0,
+	lastAccessed DATETIME,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (createdBy)
+        REFERENCES user(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (listId)
+        REFERENCES list(id)
+        ON DELETE CASCADE,
+    UNIQUE (hash),
+    INDEX (workspaceId, createdBy)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableAuditCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.audit (
+	id INT(10) NOT NULL AUTO_INCREMENT,
+	workspaceId INT(6) NOT NULL,
+	actorId INT(6) NOT NULL,
+	action VARCHAR(30) NOT NULL,
+	itemId INT(6) NOT NULL,
+	changes MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    INDEX (workspaceId, itemId),
+    INDEX (workspaceId, created)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+const tableItemVersionCreationQuery = `CREATE TABLE IF NOT EXISTS todolist.itemVersion (
+	workspaceId INT(6) NOT NULL,
+	itemId INT(6) NOT NULL,
+	version INT(6) NOT NULL,
+	actorId INT(6) NOT NULL,
+	snapshot MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	created DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (itemId, version),
+    FOREIGN KEY (workspaceId)
+        REFERENCES workspace(id)
+        ON DELETE CASCADE,
+    FOREIGN KEY (itemId)
+        REFERENCES item(id)
+        ON DELETE CASCADE
 )ENGINE=InnoDB DEFAULT CHARSET=utf8;`
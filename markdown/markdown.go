@@ -0,0 +1,31 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// renderer converts GitHub flavored Markdown, including task lists, to HTML
+var renderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy keeps the HTML safe to embed in a web page: raw HTML of the source, scripts, event handlers
+// and javascript links are removed, the disabled checkboxes of the task lists are kept
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// Render returns the sanitized HTML of the Markdown source
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,61 @@
+package mention
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Mention defines the structure of a notification of a user mentioned in a comment or in the description of an item
+// CommentID is zero for the mentions in a description
+type Mention struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	ItemID    int        `json:"itemId"`
+	Item      string     `json:"item"`
+	CommentID int        `json:"commentId,omitempty"`
+	AuthorID  int        `json:"authorId"`
+	Author    string     `json:"author"`
+	Text      string     `json:"text"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// MaxLimit is the largest number of mentions returned by one query
+const MaxLimit = 100
+
+// pattern matches @username preceded by the start of the text or by a character which can not be part of
+// an email address, the usernames follow the rules of user.Validate
+var pattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_.@-])@([a-zA-Z0-9_.-]{3,30})`)
+
+// Parse returns the usernames mentioned in the text in the order of their first mention
+// A dot ending a sentence is not taken as part of the username
+func Parse(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimRight(m[1], ".")
+		if len(name) < 3 || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// Added returns the usernames mentioned in the text which were not mentioned in the previous one
+// Only the new mentions of an edited text are notified
+func Added(previous, text string) []string {
+	before := make(map[string]bool)
+	for _, name := range Parse(previous) {
+		before[strings.ToLower(name)] = true
+	}
+	var names []string
+	for _, name := range Parse(text) {
+		if !before[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -1,38 +1,411 @@
 CREATE DATABASE IF NOT EXISTS `todolist`;
 
+CREATE TABLE IF NOT EXISTS `todolist`.`user` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`username` VARCHAR(30) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`email` VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`password` VARCHAR(60) NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE (`username`),
+    UNIQUE (`email`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workspace` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`slug` VARCHAR(31) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE (`slug`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+INSERT IGNORE INTO `todolist`.`workspace` (`id`, `slug`, `name`) VALUES (1, 'default', 'Default');
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workspaceMember` (
+	`workspaceId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`role` ENUM('member', 'admin') NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`workspaceId`, `userId`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`apiKey` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`userId` INT(6) NOT NULL,
+	`workspaceId` INT(6) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`prefix` CHAR(8) NOT NULL,
+	`hash` CHAR(64) NOT NULL,
+	`scopes` VARCHAR(255) NOT NULL,
+	`lastUsed` DATETIME,
+	`revoked` DATETIME,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`prefix`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`list` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`ownerId` INT(6) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`description` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	`color` VARCHAR(7) NOT NULL DEFAULT '',
+	`archived` BOOLEAN NOT NULL DEFAULT false,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`ownerId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`workspaceId`, `ownerId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`listMember` (
+	`listId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`role` ENUM('viewer', 'commenter', 'editor') NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`listId`, `userId`),
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workflowState` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`listId` INT(6) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`position` INT(6) NOT NULL,
+	`done` BOOLEAN NOT NULL DEFAULT false,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`listId`, `name`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workflowTransition` (
+	`listId` INT(6) NOT NULL,
+	`fromId` INT(6) NOT NULL,
+	`toId` INT(6) NOT NULL,
+    PRIMARY KEY (`fromId`, `toId`),
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`fromId`)
+        REFERENCES `workflowState`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`toId`)
+        REFERENCES `workflowState`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`listId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
 CREATE TABLE IF NOT EXISTS `todolist`.`item` (
 	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`ownerId` INT(6) NOT NULL,
+	`listId` INT(6),
 	`title` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
 	`description` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci,
 	`status` BOOLEAN NOT NULL DEFAULT false,
+	`stateId` INT(6),
+	`position` VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '',
 	`due` DATETIME,
+	`start` DATETIME,
+	`snoozed` DATETIME,
+	`deferred` BOOLEAN NOT NULL DEFAULT false,
+	`estimate` INT NOT NULL DEFAULT 0,
+	`completed` DATETIME,
+	`archived` DATETIME,
+	`deleted` DATETIME,
 	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	`updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    PRIMARY KEY (`id`)
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`ownerId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE SET NULL,
+    FOREIGN KEY (`stateId`)
+        REFERENCES `workflowState`(`id`)
+        ON DELETE SET NULL,
+    INDEX (`workspaceId`, `ownerId`),
+    INDEX (`listId`, `position`),
+    INDEX (`deleted`),
+    INDEX (`completed`),
+    INDEX (`deferred`)
 )ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
 CREATE TABLE IF NOT EXISTS `todolist`.`comment` (
     `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
     `itemId` INT(6) NOT NULL,
+    `authorId` INT(6),
+    `parentId` INT(6),
     `comment` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `edited` BOOLEAN NOT NULL DEFAULT false,
     `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
     `updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
     FOREIGN KEY (`itemId`) 
         REFERENCES `item`(`id`) 
         ON DELETE CASCADE,
+    FOREIGN KEY (`authorId`)
+        REFERENCES `user`(`id`)
+        ON DELETE SET NULL,
+    FOREIGN KEY (`parentId`)
+        REFERENCES `comment`(`id`)
+        ON DELETE CASCADE,
     INDEX (`itemId`)
 );
 
-CREATE TABLE IF NOT EXISTS `todolist`.`label` (
+CREATE TABLE IF NOT EXISTS `todolist`.`commentEdit` (
     `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `commentId` INT(6) NOT NULL,
+    `comment` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`commentId`)
+        REFERENCES `comment`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`commentId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`checklist` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
     `itemId` INT(6) NOT NULL,
-    `label` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `text` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `checked` BOOLEAN NOT NULL DEFAULT false,
+    `position` INT NOT NULL DEFAULT 0,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`, `position`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`attachment` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `uploaderId` INT(6) NOT NULL,
+    `name` VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `contentType` VARCHAR(255) NOT NULL,
+    `size` BIGINT NOT NULL,
+    `blobKey` CHAR(64) NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`),
+    INDEX (`blobKey`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`timeEntry` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`started` DATETIME NOT NULL,
+	`ended` DATETIME,
+	`duration` INT,
+	`note` VARCHAR(200) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`),
+    INDEX (`userId`, `ended`),
+    INDEX (`workspaceId`, `started`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemDependency` (
+    `workspaceId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `blockerId` INT(6) NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `blockerId`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`blockerId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`blockerId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`mention` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `userId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `commentId` INT(6),
+    `authorId` INT(6) NOT NULL,
+    `readAt` DATETIME,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`commentId`)
+        REFERENCES `comment`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`, `workspaceId`, `readAt`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`label` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `name` VARCHAR(100) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `color` VARCHAR(7) NOT NULL DEFAULT '',
+    `description` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+    `uses` INT NOT NULL DEFAULT 0,
+    `lastUsed` DATETIME,
     `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
     `updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`workspaceId`, `name`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemLabel` (
+    `itemId` INT(6) NOT NULL,
+    `labelId` INT(6) NOT NULL,
+    `position` INT(6) NOT NULL DEFAULT 0,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `labelId`),
     FOREIGN KEY (`itemId`)
         REFERENCES `item`(`id`)
         ON DELETE CASCADE,
-    INDEX (`itemId`)
-);
\ No newline at end of file
+    FOREIGN KEY (`labelId`)
+        REFERENCES `label`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`labelId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`share` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`createdBy` INT(6) NOT NULL,
+	`itemId` INT(6),
+	`listId` INT(6),
+	`hash` CHAR(64) NOT NULL,
+	`expires` DATETIME NOT NULL,
+	`revoked` DATETIME,
+	`accessCount` INT NOT NULL DEFAULT 0,
+	`lastAccessed` DATETIME,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`createdBy`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`hash`),
+    INDEX (`workspaceId`, `createdBy`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- the audit log is append-only, the entries outlive the items and users they refer to
+CREATE TABLE IF NOT EXISTS `todolist`.`audit` (
+	`id` INT(10) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`actorId` INT(6) NOT NULL,
+	`action` VARCHAR(30) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`changes` MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`workspaceId`, `itemId`),
+    INDEX (`workspaceId`, `created`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemVersion` (
+	`workspaceId` INT(6) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`version` INT(6) NOT NULL,
+	`actorId` INT(6) NOT NULL,
+	`snapshot` MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `version`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,21 @@
+-- Adds the lists grouping the items.
+-- The schema in mysql-init only creates new databases, run the files of this directory in order once on the databases
+-- created before, starting with this one. The existing items are in no list.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`list` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`description` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	`color` VARCHAR(7) NOT NULL DEFAULT '',
+	`archived` BOOLEAN NOT NULL DEFAULT false,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `listId` INT(6) AFTER `id`,
+    ADD FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE SET NULL,
+    ADD INDEX (`listId`);
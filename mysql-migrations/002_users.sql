@@ -0,0 +1,53 @@
+-- Adds the user accounts owning the items and the lists, the API keys and the members of the shared lists.
+-- Run this file once on the databases created before, after 001_lists.sql. The existing items and lists are given
+-- to a new account, set its username, email and the bcrypt hash of its password before running the file, e.g.
+--   SET @username = 'admin', @email = 'admin@example.com', @password = '$2a$10$...';
+-- The hash is generated with `htpasswd -bnBC 10 "" secret | tr -d ':\n'`.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`user` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`username` VARCHAR(30) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`email` VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`password` VARCHAR(60) NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE (`username`),
+    UNIQUE (`email`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+INSERT INTO `todolist`.`user` (username, email, password) VALUES (@username, @email, @password);
+SET @owner = LAST_INSERT_ID();
+
+ALTER TABLE `todolist`.`list` ADD COLUMN `ownerId` INT(6) AFTER `id`;
+UPDATE `todolist`.`list` SET ownerId=@owner;
+ALTER TABLE `todolist`.`list`
+    MODIFY `ownerId` INT(6) NOT NULL,
+    ADD FOREIGN KEY (`ownerId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    ADD INDEX (`ownerId`);
+
+ALTER TABLE `todolist`.`item` ADD COLUMN `ownerId` INT(6) AFTER `id`;
+UPDATE `todolist`.`item` SET ownerId=@owner;
+ALTER TABLE `todolist`.`item`
+    MODIFY `ownerId` INT(6) NOT NULL,
+    ADD FOREIGN KEY (`ownerId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    ADD INDEX (`ownerId`);
+
+CREATE TABLE IF NOT EXISTS `todolist`.`listMember` (
+	`listId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`role` ENUM('viewer', 'commenter', 'editor') NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`listId`, `userId`),
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
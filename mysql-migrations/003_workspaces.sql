@@ -0,0 +1,112 @@
+-- Adds the workspaces isolating the data of their members, the API keys and the share links.
+-- Run this file once on the databases created before, after 002_users.sql. The existing data is moved to the default
+-- workspace and the existing users become its admins.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workspace` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`slug` VARCHAR(31) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE (`slug`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+INSERT IGNORE INTO `todolist`.`workspace` (`id`, `slug`, `name`) VALUES (1, 'default', 'Default');
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workspaceMember` (
+	`workspaceId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`role` ENUM('member', 'admin') NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`workspaceId`, `userId`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+INSERT IGNORE INTO `todolist`.`workspaceMember` (workspaceId, userId, role) SELECT 1, id, 'admin' FROM `todolist`.`user`;
+
+-- the existing rows get the default workspace, the default value is only kept for the migration
+ALTER TABLE `todolist`.`list`
+    ADD COLUMN `workspaceId` INT(6) NOT NULL DEFAULT 1 AFTER `id`,
+    ADD FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    ADD INDEX (`workspaceId`, `ownerId`);
+ALTER TABLE `todolist`.`list` ALTER `workspaceId` DROP DEFAULT;
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `workspaceId` INT(6) NOT NULL DEFAULT 1 AFTER `id`,
+    ADD FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    ADD INDEX (`workspaceId`, `ownerId`);
+ALTER TABLE `todolist`.`item` ALTER `workspaceId` DROP DEFAULT;
+
+ALTER TABLE `todolist`.`comment`
+    ADD COLUMN `workspaceId` INT(6) NOT NULL DEFAULT 1 AFTER `id`,
+    ADD FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE;
+ALTER TABLE `todolist`.`comment` ALTER `workspaceId` DROP DEFAULT;
+
+ALTER TABLE `todolist`.`label`
+    ADD COLUMN `workspaceId` INT(6) NOT NULL DEFAULT 1 AFTER `id`,
+    ADD FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE;
+ALTER TABLE `todolist`.`label` ALTER `workspaceId` DROP DEFAULT;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`apiKey` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`userId` INT(6) NOT NULL,
+	`workspaceId` INT(6) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`prefix` CHAR(8) NOT NULL,
+	`hash` CHAR(64) NOT NULL,
+	`scopes` VARCHAR(255) NOT NULL,
+	`lastUsed` DATETIME,
+	`revoked` DATETIME,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`prefix`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`share` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`createdBy` INT(6) NOT NULL,
+	`itemId` INT(6),
+	`listId` INT(6),
+	`hash` CHAR(64) NOT NULL,
+	`expires` DATETIME NOT NULL,
+	`revoked` DATETIME,
+	`accessCount` INT NOT NULL DEFAULT 0,
+	`lastAccessed` DATETIME,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`createdBy`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`listId`)
+        REFERENCES `list`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`hash`),
+    INDEX (`workspaceId`, `createdBy`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,45 @@
+-- Adds the audit log, the item versions, the trash and the archive.
+-- Run this file once on the databases created before, after 003_workspaces.sql. The items done before are
+-- completed at their last update so the auto-archive job archives them like the items done afterwards.
+
+-- the audit log is append-only, the entries outlive the items and users they refer to
+CREATE TABLE IF NOT EXISTS `todolist`.`audit` (
+	`id` INT(10) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`actorId` INT(6) NOT NULL,
+	`action` VARCHAR(30) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`changes` TEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`workspaceId`, `itemId`),
+    INDEX (`workspaceId`, `created`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemVersion` (
+	`workspaceId` INT(6) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`version` INT(6) NOT NULL,
+	`actorId` INT(6) NOT NULL,
+	`snapshot` TEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `version`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `completed` DATETIME AFTER `due`,
+    ADD COLUMN `archived` DATETIME AFTER `completed`,
+    ADD COLUMN `deleted` DATETIME AFTER `archived`,
+    ADD INDEX (`deleted`),
+    ADD INDEX (`completed`);
+
+UPDATE `todolist`.`item` SET completed=updated WHERE status=true;
@@ -0,0 +1,46 @@
+-- Moves the per item labels to the labels shared by the items of a workspace.
+-- Run this file once on the databases created before, after 004_audit_trash_archive.sql.
+
+RENAME TABLE `todolist`.`label` TO `todolist`.`itemLabelText`;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`label` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `name` VARCHAR(100) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `color` VARCHAR(7) NOT NULL DEFAULT '',
+    `description` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, 
+    `updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`workspaceId`, `name`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemLabel` (
+    `itemId` INT(6) NOT NULL,
+    `labelId` INT(6) NOT NULL,
+    `position` INT(6) NOT NULL DEFAULT 0,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `labelId`),
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`labelId`)
+        REFERENCES `label`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`labelId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+-- one shared label per distinct text, the texts are compared case insensitive like the unique name
+INSERT IGNORE INTO `todolist`.`label` (workspaceId, name, created)
+    SELECT workspaceId, LEFT(TRIM(label), 100), MIN(created) FROM `todolist`.`itemLabelText`
+    WHERE TRIM(label) <> '' GROUP BY workspaceId, LEFT(TRIM(label), 100);
+
+-- the position keeps the order the labels were added to the item
+INSERT IGNORE INTO `todolist`.`itemLabel` (itemId, labelId, position, created)
+    SELECT t.itemId, l.id, t.id, t.created FROM `todolist`.`itemLabelText` t
+    JOIN `todolist`.`label` l ON l.workspaceId=t.workspaceId AND l.name=LEFT(TRIM(t.label), 100);
+
+DROP TABLE `todolist`.`itemLabelText`;
@@ -0,0 +1,11 @@
+-- Adds the usage statistics ranking the label suggestions.
+-- Run this file once on the databases created before, after 005_shared_labels.sql.
+
+ALTER TABLE `todolist`.`label`
+    ADD COLUMN `uses` INT NOT NULL DEFAULT 0 AFTER `description`,
+    ADD COLUMN `lastUsed` DATETIME AFTER `uses`;
+
+UPDATE `todolist`.`label` l
+    JOIN (SELECT labelId, COUNT(*) AS uses, MAX(created) AS lastUsed FROM `todolist`.`itemLabel` GROUP BY labelId) u
+    ON u.labelId=l.id
+    SET l.uses=u.uses, l.lastUsed=u.lastUsed;
@@ -0,0 +1,22 @@
+-- Records the authors, the replies and the edit history of the comments.
+-- Run this file once on the databases created before, the existing comments keep no author.
+
+ALTER TABLE `todolist`.`comment`
+    ADD COLUMN `authorId` INT(6) AFTER `itemId`,
+    ADD COLUMN `parentId` INT(6) AFTER `authorId`,
+    ADD COLUMN `edited` BOOLEAN NOT NULL DEFAULT false AFTER `comment`,
+    ADD FOREIGN KEY (`authorId`) REFERENCES `todolist`.`user`(`id`) ON DELETE SET NULL,
+    ADD FOREIGN KEY (`parentId`) REFERENCES `todolist`.`comment`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`commentEdit` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `commentId` INT(6) NOT NULL,
+    `comment` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`commentId`)
+        REFERENCES `todolist`.`comment`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`commentId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,64 @@
+-- Replaces the boolean status of the items of a list by the states of a workflow per list.
+-- Run this file once on the databases created before, every list gets the default workflow
+-- Backlog -> In progress -> Review -> Done and its done items are moved to Done, the others to Backlog.
+-- The status column is kept, it is set for the items in a done state.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workflowState` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`listId` INT(6) NOT NULL,
+	`name` VARCHAR(50) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+	`position` INT(6) NOT NULL,
+	`done` BOOLEAN NOT NULL DEFAULT false,
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `todolist`.`workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`listId`)
+        REFERENCES `todolist`.`list`(`id`)
+        ON DELETE CASCADE,
+    UNIQUE (`listId`, `name`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`workflowTransition` (
+	`listId` INT(6) NOT NULL,
+	`fromId` INT(6) NOT NULL,
+	`toId` INT(6) NOT NULL,
+    PRIMARY KEY (`fromId`, `toId`),
+    FOREIGN KEY (`listId`)
+        REFERENCES `todolist`.`list`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`fromId`)
+        REFERENCES `todolist`.`workflowState`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`toId`)
+        REFERENCES `todolist`.`workflowState`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`listId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `stateId` INT(6) AFTER `status`,
+    ADD FOREIGN KEY (`stateId`) REFERENCES `todolist`.`workflowState`(`id`) ON DELETE SET NULL;
+
+INSERT INTO `todolist`.`workflowState`(`workspaceId`, `listId`, `name`, `position`, `done`)
+    SELECT l.`workspaceId`, l.`id`, s.`name`, s.`position`, s.`done` FROM `todolist`.`list` l
+    CROSS JOIN (
+        SELECT 'Backlog' AS `name`, 0 AS `position`, false AS `done`
+        UNION ALL SELECT 'In progress', 1, false
+        UNION ALL SELECT 'Review', 2, false
+        UNION ALL SELECT 'Done', 3, true
+    ) s;
+
+INSERT INTO `todolist`.`workflowTransition`(`listId`, `fromId`, `toId`)
+    SELECT f.`listId`, f.`id`, t.`id` FROM `todolist`.`workflowState` f
+    JOIN `todolist`.`workflowState` t ON t.`listId`=f.`listId`
+    WHERE (f.`name`, t.`name`) IN (
+        ('Backlog', 'In progress'), ('In progress', 'Backlog'), ('In progress', 'Review'),
+        ('Review', 'In progress'), ('Review', 'Done'), ('Done', 'In progress')
+    );
+
+UPDATE `todolist`.`item` i
+    JOIN `todolist`.`workflowState` s ON s.`listId`=i.`listId` AND s.`name`=IF(i.`status`, 'Done', 'Backlog')
+    SET i.`stateId`=s.`id`;
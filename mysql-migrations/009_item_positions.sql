@@ -0,0 +1,16 @@
+-- Adds the manual order of the items, see the rank package.
+-- Run this file once on the databases created before, the items get ranks in the order they were created,
+-- per list and per owner for the items without a list. The ranks are written as 6 base 36 digits.
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `position` VARCHAR(255) CHARACTER SET ascii COLLATE ascii_bin NOT NULL DEFAULT '' AFTER `stateId`,
+    DROP INDEX `listId`,
+    ADD INDEX (`listId`, `position`);
+
+-- a rank must not end with 0, the trailing zeros are removed
+UPDATE `todolist`.`item` i
+    JOIN (
+        SELECT `id`, ROW_NUMBER() OVER (PARTITION BY `listId`, IF(`listId` IS NULL, `ownerId`, 0) ORDER BY `id`) AS n
+        FROM `todolist`.`item`
+    ) r ON r.`id`=i.`id`
+    SET i.`position`=TRIM(TRAILING '0' FROM LPAD(LOWER(CONV(r.n, 10, 36)), 6, '0'));
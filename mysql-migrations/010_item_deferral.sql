@@ -0,0 +1,9 @@
+-- Adds the start dates and the snooze of the items.
+-- Run this file once on the databases created before, the existing items have neither of them.
+-- deferred is set while an item waits for its start date or snooze, the resurface job clears it.
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `start` DATETIME AFTER `due`,
+    ADD COLUMN `snoozed` DATETIME AFTER `start`,
+    ADD COLUMN `deferred` BOOLEAN NOT NULL DEFAULT false AFTER `snoozed`,
+    ADD INDEX (`deferred`);
@@ -0,0 +1,30 @@
+-- Adds the estimates of the items and the time tracked on them.
+-- Run this file once on the databases created before, the existing items have no estimate.
+
+ALTER TABLE `todolist`.`item`
+    ADD COLUMN `estimate` INT NOT NULL DEFAULT 0 AFTER `deferred`;
+
+CREATE TABLE IF NOT EXISTS `todolist`.`timeEntry` (
+	`id` INT(6) NOT NULL AUTO_INCREMENT,
+	`workspaceId` INT(6) NOT NULL,
+	`itemId` INT(6) NOT NULL,
+	`userId` INT(6) NOT NULL,
+	`started` DATETIME NOT NULL,
+	`ended` DATETIME,
+	`duration` INT,
+	`note` VARCHAR(200) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL DEFAULT '',
+	`created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `todolist`.`workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `todolist`.`item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `todolist`.`user`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`),
+    INDEX (`userId`, `ended`),
+    INDEX (`workspaceId`, `started`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,7 @@
+-- Widens the audit changes and the item versions, the snapshots hold every comment and checklist entry of the item
+-- and outgrow a TEXT column.
+-- Run this file once on the databases created before.
+
+ALTER TABLE `todolist`.`audit` MODIFY `changes` MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL;
+
+ALTER TABLE `todolist`.`itemVersion` MODIFY `snapshot` MEDIUMTEXT CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL;
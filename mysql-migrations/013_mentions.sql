@@ -0,0 +1,27 @@
+-- Adds the inbox of the users mentioned in the comments and descriptions of the items.
+-- Run this file once on the databases created before.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`mention` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `userId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `commentId` INT(6),
+    `authorId` INT(6) NOT NULL,
+    `readAt` DATETIME,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`userId`)
+        REFERENCES `user`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`commentId`)
+        REFERENCES `comment`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`userId`, `workspaceId`, `readAt`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
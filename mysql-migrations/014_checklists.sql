@@ -0,0 +1,21 @@
+-- Adds the checklists of the items.
+-- Run this file once on the databases created before.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`checklist` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `text` VARCHAR(500) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `checked` BOOLEAN NOT NULL DEFAULT false,
+    `position` INT NOT NULL DEFAULT 0,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`, `position`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
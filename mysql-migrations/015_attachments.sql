@@ -0,0 +1,23 @@
+-- Adds the attachments of the items, their content is stored under ATTACHMENTS_DIR.
+-- Run this file once on the databases created before.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`attachment` (
+    `id` INT(6) NOT NULL AUTO_INCREMENT,
+    `workspaceId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `uploaderId` INT(6) NOT NULL,
+    `name` VARCHAR(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `contentType` VARCHAR(255) NOT NULL,
+    `size` BIGINT NOT NULL,
+    `blobKey` CHAR(64) NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`itemId`),
+    INDEX (`blobKey`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
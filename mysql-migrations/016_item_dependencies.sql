@@ -0,0 +1,20 @@
+-- Adds the dependencies between the items.
+-- Run this file once on the databases created before.
+
+CREATE TABLE IF NOT EXISTS `todolist`.`itemDependency` (
+    `workspaceId` INT(6) NOT NULL,
+    `itemId` INT(6) NOT NULL,
+    `blockerId` INT(6) NOT NULL,
+    `created` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`itemId`, `blockerId`),
+    FOREIGN KEY (`workspaceId`)
+        REFERENCES `workspace`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`itemId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    FOREIGN KEY (`blockerId`)
+        REFERENCES `item`(`id`)
+        ON DELETE CASCADE,
+    INDEX (`blockerId`)
+)ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,82 @@
+// Package rank generates the lexicographic ranks ordering the items manually
+// A rank is a base 36 fraction written with the digits 0-9a-z, the ranks compare as strings so moving an item
+// only changes its own rank. The ranks never end with 0 so there is always a rank between two different ones.
+package rank
+
+import "strings"
+
+// MaxLength is the longest rank, a rank growing longer means the ranks around it have to be spread again
+const MaxLength = 255
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// width is the number of digits of the ranks appended by After
+const width = 6
+
+// Between returns the shortest rank sorting after a and before b, an empty a stands for the start of the ranks
+// and an empty b for their end
+// Returns an empty rank when a does not sort before b
+func Between(a, b string) string {
+	if b != "" && a >= b {
+		return ""
+	}
+	return midpoint(a, b)
+}
+
+// After returns a rank sorting after a which leaves room for many more ranks after it, it is used to append
+func After(a string) string {
+	d := []byte(strings.Repeat("0", width))
+	copy(d, a)
+	for k := width - 1; k >= 0; k-- {
+		if i := strings.IndexByte(digits, d[k]); i < base-1 {
+			d[k] = digits[i+1]
+			return strings.TrimRight(string(d), "0")
+		}
+		d[k] = '0'
+	}
+	return midpoint(a, "")
+}
+
+// midpoint returns a rank between a and b, a is read as if it was followed by zeros
+func midpoint(a, b string) string {
+	if b != "" {
+		// the common prefix is kept
+		n := 0
+		for n < len(b) && digit(a, n) == strings.IndexByte(digits, b[n]) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(suffix(a, n), b[n:])
+		}
+	}
+	da := digit(a, 0)
+	db := base
+	if b != "" {
+		db = strings.IndexByte(digits, b[0])
+	}
+	if db-da > 1 {
+		return string(digits[(da+db)/2])
+	}
+	// the first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[da]) + midpoint(suffix(a, 1), "")
+}
+
+// digit returns the value of the digit n of the rank, 0 past its end
+func digit(r string, n int) int {
+	if n >= len(r) {
+		return 0
+	}
+	return strings.IndexByte(digits, r[n])
+}
+
+func suffix(r string, n int) string {
+	if n >= len(r) {
+		return ""
+	}
+	return r[n:]
+}
@@ -0,0 +1,64 @@
+package rank
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"", ""},
+		{"", "1"},
+		{"", "01"},
+		{"", "001"},
+		{"1", ""},
+		{"a", "b"},
+		{"az", "b"},
+		{"a", "a1"},
+		{"a1", "a2"},
+		{"y", "z"},
+		{"z", ""},
+		{"zz", ""},
+		{"0z", "1"},
+		{"i", "i01"},
+	}
+	for _, tt := range tests {
+		r := Between(tt.a, tt.b)
+		if r <= tt.a || (tt.b != "" && r >= tt.b) {
+			t.Errorf("Between(%q, %q) = %q, expected a rank between them", tt.a, tt.b, r)
+		}
+		if strings.HasSuffix(r, "0") {
+			t.Errorf("Between(%q, %q) = %q, expected no trailing 0", tt.a, tt.b, r)
+		}
+	}
+}
+
+func TestBetweenUnordered(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"a", "a"},
+		{"b", "a"},
+		{"b", "az"},
+	}
+	for _, tt := range tests {
+		if r := Between(tt.a, tt.b); r != "" {
+			t.Errorf("Between(%q, %q) = %q, expected an empty rank", tt.a, tt.b, r)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	tests := []string{"", "1", "i", "00001", "zzzzzy", "zzzzzz", "zzzzzzzz"}
+	for _, a := range tests {
+		r := After(a)
+		if r <= a {
+			t.Errorf("After(%q) = %q, expected a rank after it", a, r)
+		}
+		if strings.HasSuffix(r, "0") {
+			t.Errorf("After(%q) = %q, expected no trailing 0", a, r)
+		}
+	}
+}
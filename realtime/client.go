@@ -0,0 +1,127 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// time allowed to write a message to the client
+	writeWait = 10 * time.Second
+	// time allowed to read the next pong message from the client
+	pongWait = 60 * time.Second
+	// send pings to the client with this period, it must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+	// maximum size of a message sent by the client
+	maxMessageSize = 1024
+	// number of messages queued for a client before it is considered too slow
+	sendBufferSize = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// client is a websocket connection subscribed to one topic
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	topic  string
+	listID int
+	user   string
+}
+
+// Serve upgrades the http connection to a websocket and subscribes it to the topic
+// The list and the user are attached to the presence and typing messages sent by the client
+func (h *Hub) Serve(w http.ResponseWriter, r *http.Request, topic string, listID int, user string) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	c := &client{
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, sendBufferSize),
+		topic:  topic,
+		listID: listID,
+		user:   user,
+	}
+	h.register(c)
+	h.broadcast(topic, Message{Type: TypePresence, ListID: listID, User: user, State: "joined"}, c)
+
+	go c.writePump()
+	go c.readPump()
+	return nil
+}
+
+// readPump relays presence and typing messages from the client to the rest of the topic
+// Any other message type is ignored, item mutations are only published by the server
+func (c *client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.hub.broadcast(c.topic, Message{Type: TypePresence, ListID: c.listID, User: c.user, State: "left"}, nil)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println(err)
+			}
+			return
+		}
+
+		var m Message
+		if err := json.Unmarshal(b, &m); err != nil {
+			continue
+		}
+		if m.Type != TypePresence && m.Type != TypeTyping {
+			continue
+		}
+		// the sender can not impersonate other users or lists
+		c.hub.broadcast(c.topic, Message{Type: m.Type, ListID: c.listID, ItemID: m.ItemID, User: c.user, State: m.State}, c)
+	}
+}
+
+// writePump sends the queued messages and the keep alive pings to the client
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case b, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// the hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// Message types exchanged through the hub
+const (
+	TypeItemCreated    = "item.created"
+	TypeItemMoved      = "item.moved"
+	TypeItemUpdated    = "item.updated"
+	TypeItemDeleted    = "item.deleted"
+	TypeItemResurfaced = "item.resurfaced"
+	TypeMention        = "mention"
+	TypePresence       = "presence"
+	TypeTyping         = "typing"
+)
+
+// Message defines the structure of a message sent to the subscribed clients
+type Message struct {
+	Type   string          `json:"type"`
+	ListID int             `json:"listId"`
+	ItemID int             `json:"itemId,omitempty"`
+	User   string          `json:"user,omitempty"`
+	State  string          `json:"state,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Hub keeps track of the clients subscribed to each topic and broadcasts messages to them
+// A topic is a list or the items without a list of one user, see ListTopic and UserTopic
+// Every topic has its own room so a broadcast only locks the clients of one topic
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]*room
+}
+
+type room struct {
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+}
+
+// NewHub creates and sets up a new Hub
+func NewHub() *Hub {
+	return &Hub{rooms: make(map[string]*room)}
+}
+
+// ListTopic returns the topic of the items of the list
+func ListTopic(listID int) string {
+	return "list:" + strconv.Itoa(listID)
+}
+
+// UserTopic returns the topic of the items of the user in the workspace which do not belong to any list
+func UserTopic(workspaceID int, userID int) string {
+	return "user:" + strconv.Itoa(workspaceID) + ":" + strconv.Itoa(userID)
+}
+
+// Publish sends the message to all the clients subscribed to the topic
+// Data is encoded as json, it is safe to call Publish on a nil Hub
+func (h *Hub) Publish(topic string, m Message, data interface{}) {
+	if h == nil {
+		return
+	}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		m.Data = b
+	}
+	h.broadcast(topic, m, nil)
+}
+
+// Count returns the number of clients subscribed to the topic
+func (h *Hub) Count(topic string) int {
+	h.mu.RLock()
+	rm, ok := h.rooms[topic]
+	h.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	return len(rm.clients)
+}
+
+// broadcast encodes the message once and queues it for every client of the topic except skip
+// Clients which are not able to keep up are disconnected instead of blocking the others
+func (h *Hub) broadcast(topic string, m Message, skip *client) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	h.mu.RLock()
+	rm, ok := h.rooms[topic]
+	h.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var slow []*client
+	rm.mu.RLock()
+	for c := range rm.clients {
+		if c == skip {
+			continue
+		}
+		select {
+		case c.send <- b:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	rm.mu.RUnlock()
+
+	for _, c := range slow {
+		h.unregister(c)
+	}
+}
+
+func (h *Hub) register(c *client) {
+	h.mu.Lock()
+	rm, ok := h.rooms[c.topic]
+	if !ok {
+		rm = &room{clients: make(map[*client]struct{})}
+		h.rooms[c.topic] = rm
+	}
+	// the room lock is taken before releasing the hub lock so an empty room can not be removed meanwhile
+	rm.mu.Lock()
+	h.mu.Unlock()
+	rm.clients[c] = struct{}{}
+	rm.mu.Unlock()
+}
+
+// unregister removes the client from its topic and closes its send channel
+// Empty rooms are removed so the hub does not grow with topics nobody watches
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rm, ok := h.rooms[c.topic]
+	if !ok {
+		return
+	}
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	if _, ok := rm.clients[c]; !ok {
+		return
+	}
+	delete(rm.clients, c)
+	close(c.send)
+	if len(rm.clients) == 0 {
+		delete(h.rooms, c.topic)
+	}
+}
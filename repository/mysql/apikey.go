@@ -0,0 +1,119 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aflog/todolist/user"
+	"github.com/go-sql-driver/mysql"
+)
+
+const apiKeyColumns = "id, userId, workspaceId, name, prefix, hash, scopes, lastUsed, revoked, created"
+
+// apiKeyScope returns the condition restricting the apiKey table to the keys of the authenticated user
+// in the workspace of the request
+func apiKeyScope(ctx context.Context) (string, []interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return "userId=? AND workspaceId=?", []interface{}{owner, ws}, nil
+}
+
+// CreateAPIKey stores provided key of the authenticated user and returns its id
+// The key is bound to the workspace of the request
+func (r *Repository) CreateAPIKey(ctx context.Context, k user.APIKey) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.ExecContext(ctx, "INSERT INTO apiKey(userId, workspaceId, name, prefix, hash, scopes) VALUES (?, ?, ?, ?, ?, ?)", owner, ws, k.Name, k.Prefix, k.Hash, strings.Join(k.Scopes, ","))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetAPIKeys returns all the keys of the authenticated user including the revoked ones
+func (r *Repository) GetAPIKeys(ctx context.Context) ([]user.APIKey, error) {
+	scope, scopeArgs, err := apiKeyScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getAPIKeys(ctx, "SELECT "+apiKeyColumns+" FROM apiKey WHERE "+scope+" ORDER BY id", scopeArgs...)
+}
+
+// GetAPIKeyByPrefix returns the key with the provided prefix and nil if it doesn't exist
+// It is not scoped to a user nor a workspace as it is used to authenticate the request
+func (r *Repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*user.APIKey, error) {
+	keys, err := r.getAPIKeys(ctx, "SELECT "+apiKeyColumns+" FROM apiKey WHERE prefix=? LIMIT 1", prefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return &keys[0], nil
+}
+
+// RevokeAPIKey disables the key of the authenticated user, returns false if the key doesn't exist
+func (r *Repository) RevokeAPIKey(ctx context.Context, id int) (bool, error) {
+	scope, scopeArgs, err := apiKeyScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE apiKey SET revoked=COALESCE(revoked, NOW()) WHERE id=? AND "+scope, withScope(scopeArgs, id)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIKey records the last use of the key
+func (r *Repository) TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE apiKey SET lastUsed=? WHERE id=?", usedAt, id)
+	return err
+}
+
+func (r *Repository) getAPIKeys(ctx context.Context, query string, args ...interface{}) ([]user.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []user.APIKey{}
+	for rows.Next() {
+		k := user.APIKey{}
+		var scopes string
+		lastUsed := mysql.NullTime{}
+		revoked := mysql.NullTime{}
+		if err := rows.Scan(&k.ID, &k.UserID, &k.WorkspaceID, &k.Name, &k.Prefix, &k.Hash, &scopes, &lastUsed, &revoked, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		k.Scopes = strings.Split(scopes, ",")
+		if lastUsed.Valid {
+			k.LastUsedAt = &lastUsed.Time
+		}
+		if revoked.Valid {
+			k.RevokedAt = &revoked.Time
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
@@ -0,0 +1,129 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aflog/todolist/audit"
+)
+
+// ArchiveItems archives the provided items and returns the number of archived items
+// Items the authenticated user can not change or which are already archived are skipped
+func (r *Repository) ArchiveItems(ctx context.Context, ids []int) (int, error) {
+	return r.setArchived(ctx, ids, true)
+}
+
+// UnarchiveItems takes the provided items out of the archive and returns the number of unarchived items
+// Items the authenticated user can not change or which are not archived are skipped
+func (r *Repository) UnarchiveItems(ctx context.Context, ids []int) (int, error) {
+	return r.setArchived(ctx, ids, false)
+}
+
+// ArchiveCompletedItems archives the items done before the provided time in all the workspaces
+// and returns the number of archived items, each of them is recorded in the audit log without an actor
+// It is not scoped as it is run by the auto-archive job, the items locked by another transaction are left for the next run
+func (r *Repository) ArchiveCompletedItems(ctx context.Context, completedBefore time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, workspaceId FROM item WHERE status=true AND completed<? AND archived IS NULL AND deleted IS NULL FOR UPDATE SKIP LOCKED", completedBefore)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	// the workspace of each item the audit entry belongs to
+	items := make(map[int]int)
+	for rows.Next() {
+		var id, ws int
+		if err := rows.Scan(&id, &ws); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return 0, err
+		}
+		items[id] = ws
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for id, ws := range items {
+		if _, err := tx.ExecContext(ctx, "UPDATE item SET archived=NOW() WHERE id=?", id); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		before := map[string]bool{"archived": false}
+		after := map[string]bool{"archived": true}
+		if err := insertAudit(ctx, tx, ws, 0, audit.ActionItemArchived, id, before, after); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return int64(len(items)), tx.Commit()
+}
+
+// setArchived archives or unarchives the items in one transaction and records each of them in the audit log
+func (r *Repository) setArchived(ctx context.Context, ids []int, archived bool) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	scope, scopeArgs, err := editScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+	idsStr := make([]string, len(ids))
+	for i, value := range ids {
+		idsStr[i] = strconv.Itoa(value)
+	}
+	state, value, action := "item.archived IS NOT NULL", "NULL", audit.ActionItemUnarchived
+	if archived {
+		state, value, action = "item.archived IS NULL", "NOW()", audit.ActionItemArchived
+	}
+	condition := fmt.Sprintf("item.id IN(%s) AND %s AND %s", strings.Join(idsStr, ", "), state, scope)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT item.id FROM item WHERE "+condition+" FOR UPDATE", scopeArgs...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	changed := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return 0, err
+		}
+		changed = append(changed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if len(changed) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE item SET archived="+value+" WHERE "+condition, scopeArgs...); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	for _, id := range changed {
+		before := map[string]bool{"archived": !archived}
+		after := map[string]bool{"archived": archived}
+		if err := writeAudit(ctx, tx, action, id, before, after); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	return len(changed), tx.Commit()
+}
@@ -0,0 +1,158 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+)
+
+// attachmentSelect reads the attachments together with the username of the uploader
+const attachmentSelect = `SELECT a.id, a.itemId, a.name, a.contentType, a.size, a.uploaderId, COALESCE(u.username, ''), a.blobKey, a.created
+	FROM attachment a LEFT JOIN user u ON u.id=a.uploaderId`
+
+// CreateAttachment stores the metadata of the file attached to the item and returns its id
+// The content must already be in the blob store
+func (r *Repository) CreateAttachment(ctx context.Context, a item.Attachment) (int, error) {
+	uploader, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	i, err := r.lockItem(ctx, tx, a.ItemID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if i == nil {
+		tx.Rollback()
+		return 0, errNoItem
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO attachment(workspaceId, itemId, uploaderId, name, contentType, size, blobKey) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		ws, a.ItemID, uploader, a.Name, a.ContentType, a.Size, a.Key)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	a.ID = int(id)
+	a.UploaderID = uploader
+	if err := writeAudit(ctx, tx, audit.ActionAttachmentAdded, a.ItemID, nil, a); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return a.ID, tx.Commit()
+}
+
+// GetAttachments returns the attachments of the item from the oldest one
+func (r *Repository) GetAttachments(ctx context.Context, itemID int) ([]item.Attachment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getAttachments(ctx, attachmentSelect+" WHERE a.itemId=? AND a.workspaceId=? ORDER BY a.id", itemID, ws)
+}
+
+// GetAttachment returns the attachment of the item corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetAttachment(ctx context.Context, itemID int, id int) (*item.Attachment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := r.getAttachments(ctx, attachmentSelect+" WHERE a.id=? AND a.itemId=? AND a.workspaceId=?", id, itemID, ws)
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+	return &attachments[0], nil
+}
+
+// DeleteAttachment removes the attachment of the item, returns false if the attachment doesn't exist
+// The content is left in the blob store until it is collected as unused, see UsedBlobKeys
+func (r *Repository) DeleteAttachment(ctx context.Context, itemID int, id int) (bool, error) {
+	a, err := r.GetAttachment(ctx, itemID, id)
+	if err != nil || a == nil {
+		return false, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	i, err := r.lockItem(ctx, tx, itemID)
+	if err != nil || i == nil {
+		tx.Rollback()
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM attachment WHERE id=? AND itemId=?", id, itemID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionAttachmentDeleted, itemID, a, nil); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UsedBlobKeys returns which of the provided blob keys are referenced by an attachment in any workspace
+// It is not scoped as it is run by the job removing the unused content from the blob store
+func (r *Repository) UsedBlobKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	used := make(map[string]bool)
+	if len(keys) == 0 {
+		return used, nil
+	}
+	args := make([]interface{}, len(keys))
+	for k, key := range keys {
+		args[k] = key
+	}
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT blobKey FROM attachment WHERE blobKey IN (%s)", strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		used[key] = true
+	}
+	return used, rows.Err()
+}
+
+func (r *Repository) getAttachments(ctx context.Context, query string, args ...interface{}) ([]item.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []item.Attachment{}
+	for rows.Next() {
+		var a item.Attachment
+		if err := rows.Scan(&a.ID, &a.ItemID, &a.Name, &a.ContentType, &a.Size, &a.UploaderID, &a.Uploader, &a.Key, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
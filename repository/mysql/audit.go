@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+)
+
+// auditSelect reads the audit entries together with the username of the actor
+const auditSelect = "SELECT a.id, a.actorId, COALESCE(u.username, ''), a.action, a.itemId, a.changes, a.created FROM audit a LEFT JOIN user u ON u.id=a.actorId"
+
+// writeAudit appends an entry with the differences between before and after to the audit log
+// It is part of the transaction of the mutation so the log and the data can not diverge
+func writeAudit(ctx context.Context, tx *sql.Tx, action string, itemID int, before, after interface{}) error {
+	actor, err := ownerID(ctx)
+	if err != nil {
+		return err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	return insertAudit(ctx, tx, ws, actor, action, itemID, before, after)
+}
+
+// insertAudit appends an entry of the workspace to the audit log, the actor 0 stands for the jobs
+func insertAudit(ctx context.Context, tx *sql.Tx, ws int, actor int, action string, itemID int, before, after interface{}) error {
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO audit(workspaceId, actorId, action, itemId, changes) VALUES (?, ?, ?, ?, ?)", ws, actor, action, itemID, b)
+	return err
+}
+
+// lockItem reads the item visible to the authenticated user inside the transaction and locks its row
+// until the end of the transaction, returns nil if the item doesn't exist
+func (r *Repository) lockItem(ctx context.Context, tx *sql.Tx, id int) (*item.Item, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.queryItems(ctx, tx, "SELECT "+itemColumns+" FROM item WHERE id=? AND "+scope+" LIMIT 1 FOR UPDATE", withScope(scopeArgs, id)...)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// GetItemHistory returns the newest audit entries of the item older than the entry before, from the oldest one
+// A zero before returns the newest entries
+func (r *Repository) GetItemHistory(ctx context.Context, itemID int, before int) ([]audit.Entry, error) {
+	return r.GetAuditEntries(ctx, audit.Filter{ItemID: itemID, Limit: audit.MaxLimit, Before: before, Latest: true})
+}
+
+// GetAuditEntries returns the audit entries of the workspace matching the filter from the oldest one
+func (r *Repository) GetAuditEntries(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := auditSelect + " WHERE a.workspaceId=?"
+	args := []interface{}{ws}
+	if f.ActorID != 0 {
+		query += " AND a.actorId=?"
+		args = append(args, f.ActorID)
+	}
+	if f.ItemID != 0 {
+		query += " AND a.itemId=?"
+		args = append(args, f.ItemID)
+	}
+	if f.Action != "" {
+		query += " AND a.action=?"
+		args = append(args, f.Action)
+	}
+	if !f.Since.IsZero() {
+		query += " AND a.created>=?"
+		args = append(args, f.Since)
+	}
+	if !f.Until.IsZero() {
+		query += " AND a.created<?"
+		args = append(args, f.Until)
+	}
+	if f.Before != 0 {
+		query += " AND a.id<?"
+		args = append(args, f.Before)
+	}
+	if f.Limit <= 0 || f.Limit > audit.MaxLimit {
+		f.Limit = audit.MaxLimit
+	}
+	order := " ORDER BY a.id"
+	if f.Latest {
+		order += " DESC"
+	}
+	query += order + " LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []audit.Entry{}
+	for rows.Next() {
+		e := audit.Entry{}
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Actor, &e.Action, &e.ItemID, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if f.Latest {
+		for k, l := 0, len(entries)-1; k < l; k, l = k+1, l-1 {
+			entries[k], entries[l] = entries[l], entries[k]
+		}
+	}
+	return entries, nil
+}
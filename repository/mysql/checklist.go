@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/repository"
+)
+
+// checklistColumns are the checklist columns read by getChecklists in the same order
+const checklistColumns = "id, itemId, text, checked, updated, created"
+
+// AddChecklistEntry appends the entry to the checklist of the item and returns its id
+func (r *Repository) AddChecklistEntry(ctx context.Context, itemID int, c item.ChecklistEntry) (int, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		var n int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM checklist WHERE itemId=?", itemID).Scan(&n); err != nil {
+			return err
+		}
+		if n >= item.MaxChecklist {
+			return repository.ErrChecklistFull
+		}
+		res, err := tx.ExecContext(ctx, "INSERT INTO checklist(workspaceId, itemId, text, position) SELECT ?, ?, ?, COALESCE(MAX(position)+1, 0) FROM checklist WHERE itemId=?", ws, itemID, c.Text, itemID)
+		if err != nil {
+			return err
+		}
+		created, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = int(created)
+		c.ID = id
+		return writeAudit(ctx, tx, audit.ActionChecklistAdded, itemID, nil, c)
+	})
+	return id, err
+}
+
+// ToggleChecklistEntry checks the entry or unchecks it when it is already checked
+// Returns nil if the entry doesn't exist
+func (r *Repository) ToggleChecklistEntry(ctx context.Context, itemID int, id int) (*item.ChecklistEntry, error) {
+	var after *item.ChecklistEntry
+	err := r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		checklist, err := r.lockChecklist(ctx, tx, itemID)
+		if err != nil {
+			return err
+		}
+		for _, c := range checklist {
+			if c.ID != id {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE checklist SET checked=NOT checked, updated=NOW() WHERE id=?", id); err != nil {
+				return err
+			}
+			toggled := c
+			toggled.Checked = !c.Checked
+			after = &toggled
+			return writeAudit(ctx, tx, audit.ActionChecklistToggled, itemID, c, toggled)
+		}
+		return errNoChange
+	})
+	return after, err
+}
+
+// ReorderChecklist orders the checklist of the item as the provided ids
+// The ids must list every entry of the checklist exactly once, ErrInvalidOrder is returned otherwise
+func (r *Repository) ReorderChecklist(ctx context.Context, itemID int, ids []int) error {
+	return r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		checklist, err := r.lockChecklist(ctx, tx, itemID)
+		if err != nil {
+			return err
+		}
+		if len(ids) != len(checklist) {
+			return repository.ErrInvalidOrder
+		}
+		positions := make(map[int]int, len(ids))
+		for k, id := range ids {
+			positions[id] = k
+		}
+		before := make([]int, len(checklist))
+		for k, c := range checklist {
+			if _, ok := positions[c.ID]; !ok {
+				return repository.ErrInvalidOrder
+			}
+			before[k] = c.ID
+		}
+		for k, id := range ids {
+			if _, err := tx.ExecContext(ctx, "UPDATE checklist SET position=? WHERE id=?", k, id); err != nil {
+				return err
+			}
+		}
+		return writeAudit(ctx, tx, audit.ActionChecklistReordered, itemID, checklistOrder{before}, checklistOrder{ids})
+	})
+}
+
+// DeleteChecklistEntry removes the entry from the checklist, returns false if the entry doesn't exist
+func (r *Repository) DeleteChecklistEntry(ctx context.Context, itemID int, id int) (bool, error) {
+	found := false
+	err := r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		checklist, err := r.lockChecklist(ctx, tx, itemID)
+		if err != nil {
+			return err
+		}
+		for _, c := range checklist {
+			if c.ID != id {
+				continue
+			}
+			found = true
+			if _, err := tx.ExecContext(ctx, "DELETE FROM checklist WHERE id=?", id); err != nil {
+				return err
+			}
+			return writeAudit(ctx, tx, audit.ActionChecklistDeleted, itemID, c, nil)
+		}
+		return errNoChange
+	})
+	return found, err
+}
+
+// checklistOrder is recorded in the audit log when a checklist is reordered
+type checklistOrder struct {
+	Order []int `json:"order"`
+}
+
+// addChecklist stores the checklist of a new item inside its transaction
+func addChecklist(ctx context.Context, tx *sql.Tx, ws int, itemID int, checklist []item.ChecklistEntry) error {
+	for k, c := range checklist {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO checklist(workspaceId, itemId, text, checked, position) VALUES (?, ?, ?, ?, ?)", ws, itemID, c.Text, c.Checked, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockChecklist reads the checklist of the item inside the transaction and locks its rows
+func (r *Repository) lockChecklist(ctx context.Context, tx *sql.Tx, itemID int) ([]item.ChecklistEntry, error) {
+	checklists, err := r.getChecklists(ctx, tx, "SELECT "+checklistColumns+" FROM checklist WHERE itemId=? ORDER BY position, id FOR UPDATE", itemID)
+	if err != nil {
+		return nil, err
+	}
+	return checklists[itemID], nil
+}
+
+func (r *Repository) getChecklistsByID(ctx context.Context, q querier, itemIds []int) (map[int][]item.ChecklistEntry, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	itemIdsStr := make([]string, len(itemIds))
+	for i, value := range itemIds {
+		itemIdsStr[i] = strconv.Itoa(value)
+	}
+	sqlStatement := fmt.Sprintf("SELECT "+checklistColumns+" FROM checklist WHERE workspaceId=? AND itemId IN(%s) ORDER BY itemId, position, id", strings.Join(itemIdsStr, ", "))
+	return r.getChecklists(ctx, q, sqlStatement, ws)
+}
+
+func (r *Repository) getChecklists(ctx context.Context, q querier, sql string, args ...interface{}) (map[int][]item.ChecklistEntry, error) {
+	rows, err := q.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checklists := make(map[int][]item.ChecklistEntry)
+	for rows.Next() {
+		c := item.ChecklistEntry{}
+		if err := rows.Scan(&c.ID, &c.ItemID, &c.Text, &c.Checked, &c.UpdatedAt, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		checklists[c.ItemID] = append(checklists[c.ItemID], c)
+	}
+	return checklists, rows.Err()
+}
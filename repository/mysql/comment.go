@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/repository"
+)
+
+// commentSelect reads the comments together with the username of their author in the order scanned by getComments
+// The comments written before the authors were recorded have no author
+const commentSelect = "SELECT c.id, c.itemId, COALESCE(c.authorId, 0), COALESCE(u.username, ''), COALESCE(c.parentId, 0), c.comment, c.edited, c.created, c.updated FROM comment c LEFT JOIN user u ON u.id=c.authorId"
+
+// GetComments returns the comments of the item from the oldest one
+func (r *Repository) GetComments(ctx context.Context, itemID int) ([]item.Comment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.getComments(ctx, r.db, commentSelect+" WHERE c.itemId=? AND c.workspaceId=? ORDER BY c.id", itemID, ws)
+	if err != nil {
+		return nil, err
+	}
+	if comments[itemID] == nil {
+		return []item.Comment{}, nil
+	}
+	return comments[itemID], nil
+}
+
+// GetComment returns the comment of the item corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetComment(ctx context.Context, itemID int, id int) (*item.Comment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.getComments(ctx, r.db, commentSelect+" WHERE c.id=? AND c.itemId=? AND c.workspaceId=?", id, itemID, ws)
+	if err != nil || len(comments[itemID]) == 0 {
+		return nil, err
+	}
+	return &comments[itemID][0], nil
+}
+
+// GetCommentEdits returns the previous texts of the comment from the oldest one
+func (r *Repository) GetCommentEdits(ctx context.Context, id int) ([]item.CommentEdit, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT comment, created FROM commentEdit WHERE commentId=? AND workspaceId=? ORDER BY id", id, ws)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []item.CommentEdit{}
+	for rows.Next() {
+		var e item.CommentEdit
+		if err := rows.Scan(&e.Text, &e.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, e)
+	}
+	return edits, rows.Err()
+}
+
+// AddComment stores the comment of the item written by the current user and returns its id
+// A reply must answer a comment of the same item, ErrNoParent is returned otherwise
+func (r *Repository) AddComment(ctx context.Context, itemID int, c item.Comment) (int, error) {
+	author, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		if c.ParentID != 0 {
+			parent, err := r.lockComment(ctx, tx, itemID, c.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent == nil {
+				return repository.ErrNoParent
+			}
+		}
+		res, err := tx.ExecContext(ctx, "INSERT INTO comment(workspaceId, itemId, authorId, parentId, comment) VALUES (?, ?, ?, ?, ?)", ws, itemID, author, nullInt(c.ParentID), c.Text)
+		if err != nil {
+			return err
+		}
+		created, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = int(created)
+		c.ID = id
+		c.AuthorID = author
+		return writeAudit(ctx, tx, audit.ActionCommentAdded, itemID, nil, commentAudit(c))
+	})
+	return id, err
+}
+
+// UpdateComment replaces the text of the comment and keeps the previous one in its edit history
+// Returns false if the comment doesn't exist
+func (r *Repository) UpdateComment(ctx context.Context, itemID int, c item.Comment) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	err = r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		before, err := r.lockComment(ctx, tx, itemID, c.ID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return errNoChange
+		}
+		found = true
+		if before.Text == c.Text {
+			return errNoChange
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO commentEdit(workspaceId, commentId, comment) VALUES (?, ?, ?)", ws, c.ID, before.Text); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE comment SET comment=?, edited=true, updated=NOW() WHERE id=?", c.Text, c.ID); err != nil {
+			return err
+		}
+		after := *before
+		after.Text = c.Text
+		return writeAudit(ctx, tx, audit.ActionCommentUpdated, itemID, commentAudit(*before), commentAudit(after))
+	})
+	return found, err
+}
+
+// DeleteComment removes the comment together with its replies and edit history
+// Returns false if the comment doesn't exist
+func (r *Repository) DeleteComment(ctx context.Context, itemID int, id int) (bool, error) {
+	found := false
+	err := r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		before, err := r.lockComment(ctx, tx, itemID, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return errNoChange
+		}
+		found = true
+		if _, err := tx.ExecContext(ctx, "DELETE FROM comment WHERE id=?", id); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, audit.ActionCommentDeleted, itemID, commentAudit(*before), nil)
+	})
+	return found, err
+}
+
+// commentAudit returns the fields of the comment recorded in the audit log, the timestamps are left out
+func commentAudit(c item.Comment) interface{} {
+	return struct {
+		ID   int    `json:"id"`
+		Text string `json:"text"`
+	}{c.ID, c.Text}
+}
+
+// lockComment reads the comment of the item inside the transaction and locks its row, returns nil if it doesn't exist
+func (r *Repository) lockComment(ctx context.Context, tx *sql.Tx, itemID int, id int) (*item.Comment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.getComments(ctx, tx, commentSelect+" WHERE c.id=? AND c.itemId=? AND c.workspaceId=? FOR UPDATE OF c", id, itemID, ws)
+	if err != nil || len(comments[itemID]) == 0 {
+		return nil, err
+	}
+	return &comments[itemID][0], nil
+}
@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/repository"
+)
+
+// GetDependencies returns the items visible to the user which the item is blocked by and which it blocks
+func (r *Repository) GetDependencies(ctx context.Context, itemID int) (*item.Dependencies, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d := &item.Dependencies{}
+	d.BlockedBy, err = r.getDependencies(ctx, "SELECT item.id, COALESCE(item.listId, 0), item.title, item.status FROM itemDependency d JOIN item ON item.id=d.blockerId WHERE d.itemId=? AND "+scope+" ORDER BY item.id", withScope(scopeArgs, itemID)...)
+	if err != nil {
+		return nil, err
+	}
+	d.Blocks, err = r.getDependencies(ctx, "SELECT item.id, COALESCE(item.listId, 0), item.title, item.status FROM itemDependency d JOIN item ON item.id=d.itemId WHERE d.blockerId=? AND "+scope+" ORDER BY item.id", withScope(scopeArgs, itemID)...)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// AddDependency makes the item blocked by the blocker, both items must be visible to the user
+// Returns ErrCycle when the blocker already depends on the item and false when one of the items doesn't exist
+func (r *Repository) AddDependency(ctx context.Context, itemID int, blockerID int) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	if itemID == blockerID {
+		return false, repository.ErrCycle
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	// the dependencies of a workspace are changed one at a time so two changes can not close a cycle together
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM workspace WHERE id=? FOR UPDATE", ws); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	for _, id := range []int{itemID, blockerID} {
+		i, err := r.lockItem(ctx, tx, id)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if i == nil {
+			tx.Rollback()
+			return false, nil
+		}
+	}
+
+	// the blocker must not be blocked by the item, directly or through other items
+	var cycle bool
+	err = tx.QueryRowContext(ctx, `WITH RECURSIVE chain(id) AS (
+			SELECT blockerId FROM itemDependency WHERE itemId=?
+			UNION SELECT d.blockerId FROM itemDependency d JOIN chain c ON d.itemId=c.id
+		) SELECT EXISTS(SELECT 1 FROM chain WHERE id=?)`, blockerID, itemID).Scan(&cycle)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if cycle {
+		tx.Rollback()
+		return false, repository.ErrCycle
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT IGNORE INTO itemDependency(workspaceId, itemId, blockerId) VALUES (?, ?, ?)", ws, itemID, blockerID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	// the dependency already exists
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return err == nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionDependencyAdded, itemID, nil, dependencyAudit{blockerID}); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RemoveDependency makes the item no longer blocked by the blocker, returns false if there was no such dependency
+func (r *Repository) RemoveDependency(ctx context.Context, itemID int, blockerID int) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	i, err := r.lockItem(ctx, tx, itemID)
+	if err != nil || i == nil {
+		tx.Rollback()
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM itemDependency WHERE itemId=? AND blockerId=? AND workspaceId=?", itemID, blockerID, ws)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionDependencyRemoved, itemID, dependencyAudit{blockerID}, nil); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// dependencyAudit is recorded in the audit log of the blocked item
+type dependencyAudit struct {
+	BlockedBy int `json:"blockedBy"`
+}
+
+// getBlockedByID returns which of the items are blocked by an item which is not done
+// The blockers in the trash do not block anymore
+func (r *Repository) getBlockedByID(ctx context.Context, q querier, itemIds []int) (map[int]bool, error) {
+	itemIdsStr := make([]string, len(itemIds))
+	for i, value := range itemIds {
+		itemIdsStr[i] = strconv.Itoa(value)
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT d.itemId FROM itemDependency d JOIN item b ON b.id=d.blockerId WHERE d.itemId IN(%s) AND b.status=false AND b.deleted IS NULL", strings.Join(itemIdsStr, ", ")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		blocked[id] = true
+	}
+	return blocked, rows.Err()
+}
+
+func (r *Repository) getDependencies(ctx context.Context, query string, args ...interface{}) ([]item.Dependency, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependencies := []item.Dependency{}
+	for rows.Next() {
+		var d item.Dependency
+		if err := rows.Scan(&d.ID, &d.ListID, &d.Title, &d.Status); err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, d)
+	}
+	return dependencies, rows.Err()
+}
@@ -0,0 +1,317 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/label"
+	"github.com/aflog/todolist/repository"
+	"github.com/go-sql-driver/mysql"
+)
+
+// labelSelect reads the labels of the workspace together with the number of items tagged with them
+const labelSelect = "SELECT l.id, l.name, l.color, l.description, COUNT(i.id), l.uses, l.lastUsed, l.updated, l.created FROM label l LEFT JOIN itemLabel il ON il.labelId=l.id LEFT JOIN item i ON i.id=il.itemId AND i.deleted IS NULL WHERE l.workspaceId=?"
+
+// GetLabels returns all the labels of the workspace ordered by name
+func (r *Repository) GetLabels(ctx context.Context) ([]label.Label, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getSharedLabels(ctx, labelSelect+" GROUP BY l.id ORDER BY l.name", ws)
+}
+
+// GetLabel returns the label corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetLabel(ctx context.Context, id int) (*label.Label, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := r.getSharedLabels(ctx, labelSelect+" AND l.id=? GROUP BY l.id", ws, id)
+	if err != nil || len(labels) == 0 {
+		return nil, err
+	}
+	return &labels[0], nil
+}
+
+// CreateLabel stores provided label and returns its id
+// Returns repository.ErrConflict if the workspace already has a label with the same name
+func (r *Repository) CreateLabel(ctx context.Context, l label.Label) (int, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.ExecContext(ctx, "INSERT INTO label(workspaceId, name, color, description) VALUES (?, ?, ?, ?)", ws, l.Name, l.Color, l.Description)
+	if isDuplicate(err) {
+		return 0, repository.ErrConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// UpdateLabel replaces the name, color and description of the label, a new name applies to all the tagged items
+// Returns repository.ErrConflict if the workspace already has a label with the new name
+func (r *Repository) UpdateLabel(ctx context.Context, l label.Label) error {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = r.relabelItems(ctx, tx, audit.ActionLabelUpdated, l.ID, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE label SET name=?, color=?, description=?, updated=NOW() WHERE id=? AND workspaceId=?", l.Name, l.Color, l.Description, l.ID, ws)
+		return err
+	})
+	if isDuplicate(err) {
+		tx.Rollback()
+		return repository.ErrConflict
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteLabel removes the label from the workspace and from all the tagged items
+func (r *Repository) DeleteLabel(ctx context.Context, id int) error {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = r.relabelItems(ctx, tx, audit.ActionLabelDeleted, id, func() error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM label WHERE id=? AND workspaceId=?", id, ws)
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeLabels tags the items of the source label with the target label and removes the source label
+// in one transaction, returns false if one of the labels doesn't exist
+func (r *Repository) MergeLabels(ctx context.Context, sourceID int, targetID int) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM label WHERE id IN (?, ?) AND workspaceId=? FOR UPDATE", sourceID, targetID, ws).Scan(&n); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n != 2 {
+		tx.Rollback()
+		return false, nil
+	}
+	var source struct {
+		uses     int
+		lastUsed mysql.NullTime
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT uses, lastUsed FROM label WHERE id=?", sourceID).Scan(&source.uses, &source.lastUsed); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	err = r.relabelItems(ctx, tx, audit.ActionLabelMerged, sourceID, func() error {
+		// items tagged with both labels keep the position of the target label
+		if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO itemLabel(itemId, labelId, position) SELECT itemId, ?, position FROM itemLabel WHERE labelId=?", targetID, sourceID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM label WHERE id=? AND workspaceId=?", sourceID, ws)
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	// the target label adds up the usage of both labels
+	if _, err := tx.ExecContext(ctx, "UPDATE label SET uses=uses+?, lastUsed=CASE WHEN lastUsed IS NULL OR lastUsed<? THEN ? ELSE lastUsed END, updated=NOW() WHERE id=?", source.uses, source.lastUsed, source.lastUsed, targetID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// relabelItems runs a change of the label inside the transaction and records in the audit log the labels of every
+// item tagged with it before and after the change, the items are locked until the end of the transaction
+// The label is shared by the workspace so the items of all its members are recorded
+func (r *Repository) relabelItems(ctx context.Context, tx *sql.Tx, action string, labelID int, change func() error) error {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM item WHERE workspaceId=? AND id IN (SELECT itemId FROM itemLabel WHERE labelId=?) ORDER BY id FOR UPDATE", ws, labelID)
+	if err != nil {
+		return err
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return change()
+	}
+
+	before, err := r.getLabelsByID(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := r.getLabelsByID(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if reflect.DeepEqual(before[id], after[id]) {
+			continue
+		}
+		if err := writeAudit(ctx, tx, action, id, labelsAudit(before[id]), labelsAudit(after[id])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelsAudit returns the labels of an item recorded in the audit log, under the same field as the item labels
+func labelsAudit(labels []item.Label) interface{} {
+	return struct {
+		Labels []item.Label `json:"labels"`
+	}{labels}
+}
+
+// tagItem assigns the labels to the item in the provided order
+// The labels are found by name in the workspace and created when they don't exist yet
+func tagItem(ctx context.Context, tx *sql.Tx, ws int, itemID int, labels []item.Label) error {
+	for k, l := range labels {
+		name := strings.TrimSpace(l.Text)
+		if name == "" {
+			continue
+		}
+		// LAST_INSERT_ID(id) makes the id of the existing label available to LastInsertId
+		res, err := tx.ExecContext(ctx, "INSERT INTO label(workspaceId, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE id=LAST_INSERT_ID(id)", ws, name)
+		if err != nil {
+			return err
+		}
+		labelID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		res, err = tx.ExecContext(ctx, "INSERT IGNORE INTO itemLabel(itemId, labelId, position) VALUES (?, ?, ?)", itemID, labelID, k)
+		if err != nil {
+			return err
+		}
+		// the usage statistics rank the suggestions, see SuggestLabels
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE label SET uses=uses+1, lastUsed=NOW() WHERE id=?", labelID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) getSharedLabels(ctx context.Context, query string, args ...interface{}) ([]label.Label, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []label.Label{}
+	for rows.Next() {
+		l := label.Label{}
+		lastUsed := mysql.NullTime{}
+		if err := rows.Scan(&l.ID, &l.Name, &l.Color, &l.Description, &l.Items, &l.Uses, &lastUsed, &l.UpdatedAt, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lastUsed.Valid {
+			l.LastUsedAt = &lastUsed.Time
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
+
+// GetLabelTree returns the hierarchy of the labels of the workspace with the number of open items of each level
+// Open items are the items visible to the user which are not done, archived nor deleted
+func (r *Repository) GetLabelTree(ctx context.Context) ([]*label.Node, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, "SELECT l.id, l.name, item.id FROM label l LEFT JOIN itemLabel il ON il.labelId=l.id LEFT JOIN item ON item.id=il.itemId AND item.status=false AND item.archived IS NULL AND "+scope+" WHERE l.workspaceId=? ORDER BY l.id", append(scopeArgs, ws)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := []label.Label{}
+	openItems := make(map[int][]int)
+	for rows.Next() {
+		l := label.Label{}
+		itemID := sql.NullInt64{}
+		if err := rows.Scan(&l.ID, &l.Name, &itemID); err != nil {
+			return nil, err
+		}
+		if len(labels) == 0 || labels[len(labels)-1].ID != l.ID {
+			labels = append(labels, l)
+		}
+		if itemID.Valid {
+			openItems[l.ID] = append(openItems[l.ID], int(itemID.Int64))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return label.Tree(labels, openItems), nil
+}
+
+// SuggestLabels returns at most limit labels of the workspace starting with the prefix
+// The labels used often and recently come first, the number of uses is divided by the number of months
+// since the last use so a label used often a long time ago ranks below a label used a few times this week
+func (r *Repository) SuggestLabels(ctx context.Context, prefix string, limit int) ([]label.Label, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the prefix condition uses the unique index on workspaceId and name, the items are not read
+	return r.getSharedLabels(ctx, "SELECT l.id, l.name, l.color, l.description, 0, l.uses, l.lastUsed, l.updated, l.created FROM label l WHERE l.workspaceId=? AND l.name LIKE ? ORDER BY l.uses / (1 + DATEDIFF(NOW(), COALESCE(l.lastUsed, l.created)) / 30) DESC, l.lastUsed DESC, l.name LIMIT ?", ws, escapeLike(prefix)+"%", limit)
+}
@@ -0,0 +1,224 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/repository"
+)
+
+// listSelect selects the lists visible to the user together with its role in them
+// It takes the user id as the first three arguments and the workspace id as the fourth one
+const listSelect = `SELECT l.id, l.ownerId, l.name, l.description, l.color, l.archived, IF(l.ownerId=?, 'owner', m.role)
+	FROM list l LEFT JOIN listMember m ON m.listId=l.id AND m.userId=?
+	WHERE (l.ownerId=? OR m.userId IS NOT NULL) AND l.workspaceId=?`
+
+// listScope returns the arguments of listSelect
+func listScope(ctx context.Context) ([]interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{owner, owner, owner, ws}, nil
+}
+
+// ownedList returns the condition restricting the list table to the lists owned by the user in the
+// workspace of the request
+func ownedList(ctx context.Context) (string, []interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return "ownerId=? AND workspaceId=?", []interface{}{owner, ws}, nil
+}
+
+// CreateList stores provided list with the default workflow and returns its id
+func (r *Repository) CreateList(ctx context.Context, l list.List) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO list(workspaceId, ownerId, name, description, color, archived) VALUES (?, ?, ?, ?, ?, ?)", ws, owner, l.Name, l.Description, l.Color, l.Archived)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := saveWorkflow(ctx, tx, ws, int(id), list.DefaultWorkflow(), make(map[string]int)); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return int(id), tx.Commit()
+}
+
+// GetLists returns all the lists owned by or shared with the user
+func (r *Repository) GetLists(ctx context.Context) ([]list.List, error) {
+	scopeArgs, err := listScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getLists(ctx, listSelect+" ORDER BY l.id", scopeArgs...)
+}
+
+// GetList returns a list corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetList(ctx context.Context, id int) (*list.List, error) {
+	scopeArgs, err := listScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lists, err := r.getLists(ctx, listSelect+" AND l.id=? LIMIT 1", append(scopeArgs, id)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, nil
+	}
+	return &lists[0], nil
+}
+
+// UpdateList replaces the stored data of the list with the provided ones
+// Only the owner of the list can update it
+func (r *Repository) UpdateList(ctx context.Context, l list.List) error {
+	scope, scopeArgs, err := ownedList(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "UPDATE list SET name=?, description=?, color=?, archived=?, updated=NOW() WHERE id=? AND "+scope, withScope(scopeArgs, l.Name, l.Description, l.Color, l.Archived, l.ID)...)
+	return err
+}
+
+// DeleteList removes the list, its items are kept without a list
+// Only the owner of the list can delete it
+func (r *Repository) DeleteList(ctx context.Context, id int) error {
+	scope, scopeArgs, err := ownedList(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "DELETE FROM list WHERE id=? AND "+scope, withScope(scopeArgs, id)...)
+	return err
+}
+
+// GetListItems returns the items of the list matching the query
+func (r *Repository) GetListItems(ctx context.Context, listID int, q item.Query) ([]item.Item, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter, filterArgs := itemFilter(q)
+	return r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE listId=? AND "+scope+filter+itemOrder(q), append(withScope(scopeArgs, listID), filterArgs...)...)
+}
+
+// GetMembers returns the users the list is shared with
+func (r *Repository) GetMembers(ctx context.Context, listID int) ([]list.Member, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT m.userId, u.username, m.role, m.created FROM listMember m
+		JOIN user u ON u.id=m.userId JOIN list l ON l.id=m.listId
+		WHERE m.listId=? AND l.workspaceId=? ORDER BY m.created`, listID, ws)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []list.Member{}
+	for rows.Next() {
+		m := list.Member{}
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMember shares the list with the user
+// Returns repository.ErrConflict if the list is already shared with the user
+// Only the owner of the list can share it
+func (r *Repository) AddMember(ctx context.Context, listID int, m list.Member) error {
+	scope, scopeArgs, err := ownedList(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO listMember(listId, userId, role) SELECT id, ?, ? FROM list WHERE id=? AND "+scope, withScope(scopeArgs, m.UserID, m.Role, listID)...)
+	if isDuplicate(err) {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+// UpdateMember changes the role of the member, returns false if the user is not a member of the list
+// Only the owner of the list can change the roles
+func (r *Repository) UpdateMember(ctx context.Context, listID int, m list.Member) (bool, error) {
+	scope, scopeArgs, err := ownedList(ctx)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE listMember SET role=? WHERE listId=? AND userId=? AND listId IN (SELECT id FROM list WHERE "+scope+")", withScope(scopeArgs, m.Role, listID, m.UserID)...)
+	if err != nil {
+		return false, err
+	}
+	// an unchanged role is not counted as affected, the membership still has to be checked
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+	var exists bool
+	err = r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM listMember WHERE listId=? AND userId=? AND listId IN (SELECT id FROM list WHERE "+scope+"))", withScope(scopeArgs, listID, m.UserID)...).Scan(&exists)
+	return exists, err
+}
+
+// RemoveMember stops sharing the list with the user, returns false if the user is not a member of the list
+func (r *Repository) RemoveMember(ctx context.Context, listID int, userID int) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, "DELETE FROM listMember WHERE listId=? AND userId=? AND listId IN (SELECT id FROM list WHERE workspaceId=?)", listID, userID, ws)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *Repository) getLists(ctx context.Context, query string, args ...interface{}) ([]list.List, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []list.List{}
+	for rows.Next() {
+		l := list.List{}
+		if err := rows.Scan(&l.ID, &l.OwnerID, &l.Name, &l.Description, &l.Color, &l.Archived, &l.Role); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+
+	return lists, rows.Err()
+}
@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aflog/todolist/mention"
+)
+
+// mentionSelect reads the mentions together with the title of the item, the username of the author and the current
+// text of the comment or of the description mentioning the user
+const mentionSelect = `SELECT m.id, m.userId, m.itemId, item.title, COALESCE(m.commentId, 0), m.authorId, COALESCE(u.username, ''),
+	COALESCE(c.comment, item.description), m.readAt, m.created
+	FROM mention m JOIN item ON item.id=m.itemId LEFT JOIN comment c ON c.id=m.commentId LEFT JOIN user u ON u.id=m.authorId`
+
+// AddMentions stores a mention of each of the users by the current user in the comment or, if commentID is zero,
+// in the description of the item and returns the stored mentions
+// Only the members of the workspace who can see the item are mentioned, the author never mentions itself
+func (r *Repository) AddMentions(ctx context.Context, itemID int, commentID int, usernames []string) ([]mention.Mention, error) {
+	author, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(usernames) == 0 {
+		return []mention.Mention{}, nil
+	}
+
+	args := []interface{}{ws, itemID, author}
+	for _, name := range usernames {
+		args = append(args, name)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the item is locked so it can not be moved to a list the users don't see meanwhile
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT u.id FROM user u
+		JOIN workspaceMember wm ON wm.userId=u.id AND wm.workspaceId=?
+		JOIN item ON item.id=? AND item.workspaceId=wm.workspaceId AND item.deleted IS NULL
+		WHERE u.id<>? AND u.username IN (%s)
+		AND (item.ownerId=u.id OR item.listId IN (SELECT id FROM list WHERE ownerId=u.id) OR item.listId IN (SELECT listId FROM listMember WHERE userId=u.id))
+		ORDER BY u.id FOR UPDATE OF item`, strings.TrimSuffix(strings.Repeat("?, ", len(usernames)), ", ")), args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	var users []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	rows.Close()
+
+	var ids []string
+	for _, user := range users {
+		res, err := tx.ExecContext(ctx, "INSERT INTO mention(workspaceId, userId, itemId, commentId, authorId) VALUES (?, ?, ?, ?, ?)", ws, user, itemID, nullInt(commentID), author)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	if len(ids) == 0 {
+		return []mention.Mention{}, tx.Commit()
+	}
+	mentions, err := r.getMentions(ctx, tx, fmt.Sprintf(mentionSelect+" WHERE m.id IN(%s) ORDER BY m.id", strings.Join(ids, ", ")))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return mentions, tx.Commit()
+}
+
+// GetMentions returns the mentions of the current user in the items it can still see, the newest first
+// When unread is set only the mentions which were not marked as read are returned
+func (r *Repository) GetMentions(ctx context.Context, unread bool, limit int) ([]mention.Mention, error) {
+	user, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := mentionSelect + " WHERE m.userId=? AND " + scope
+	if unread {
+		query += " AND m.readAt IS NULL"
+	}
+	query += " ORDER BY m.id DESC LIMIT ?"
+	return r.getMentions(ctx, r.db, query, append(withScope(scopeArgs, user), limit)...)
+}
+
+// ReadMentions marks the unread mentions of the current user as read and returns the number of marked mentions
+func (r *Repository) ReadMentions(ctx context.Context, ids []int) (int, error) {
+	user, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	idsStr := make([]string, len(ids))
+	for i, value := range ids {
+		idsStr[i] = strconv.Itoa(value)
+	}
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf("UPDATE mention SET readAt=NOW() WHERE userId=? AND workspaceId=? AND readAt IS NULL AND id IN(%s)", strings.Join(idsStr, ", ")), user, ws)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (r *Repository) getMentions(ctx context.Context, q querier, query string, args ...interface{}) ([]mention.Mention, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []mention.Mention{}
+	for rows.Next() {
+		var m mention.Mention
+		if err := rows.Scan(&m.ID, &m.UserID, &m.ItemID, &m.Item, &m.CommentID, &m.AuthorID, &m.Author, &m.Text, &m.ReadAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, m)
+	}
+	return mentions, rows.Err()
+}
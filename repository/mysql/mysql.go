@@ -3,16 +3,23 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aflog/todolist/audit"
 	"github.com/aflog/todolist/item"
 	"github.com/go-sql-driver/mysql"
 )
 
+var errNoItem = errors.New("mysql: item not found")
+
+// errNoChange is returned by a change of changeItem which did not change anything
+var errNoChange = errors.New("mysql: nothing changed")
+
 // Repository holds the data needed for storing in mysql DB
 // It implements the repository.Repository interface
 type Repository struct {
@@ -27,20 +34,44 @@ func NewRepository(db *sql.DB) *Repository {
 }
 
 // CreateItem stores provided item and returns its id
-// Item, labels and comments are stored in one transaction
+// Item, labels, comments and checklist are stored in one transaction
 func (r *Repository) CreateItem(ctx context.Context, i item.Item) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	// prepare transaction
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 
+	// the items of a list start in the requested state or in the first state matching their status
+	state, err := itemState(ctx, tx, i.ListID, "", &i, false)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// the new items are appended to the manual order
+	i.OwnerID = owner
+	i.Position, err = lastPosition(ctx, tx, &i)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
 	// insert item
 	var due *time.Time
 	if !i.DueDate.IsZero() {
 		due = &i.DueDate
 	}
-	res, err := tx.ExecContext(ctx, "INSERT INTO item(title, description, status, due) VALUES (?, ?, ?, ?)", i.Title, i.Description, i.Status, due)
+	res, err := tx.ExecContext(ctx, "INSERT INTO item(workspaceId, ownerId, listId, title, description, status, stateId, position, due, start, deferred, estimate, completed) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, IF(?, NOW(), NULL))", ws, owner, nullInt(i.ListID), i.Title, i.Description, i.Status, state, i.Position, due, i.StartDate, i.Deferred(time.Now()), i.Estimate, i.Status)
 	if err != nil {
 		tx.Rollback()
 		log.Println("item inserting")
@@ -55,7 +86,7 @@ func (r *Repository) CreateItem(ctx context.Context, i item.Item) (int, error) {
 
 	// insert comments
 	for _, c := range i.Comments {
-		_, err := tx.ExecContext(ctx, "INSERT INTO comment(itemId, comment) VALUES (?, ?)", createdID, c.Text)
+		_, err := tx.ExecContext(ctx, "INSERT INTO comment(workspaceId, itemId, authorId, comment) VALUES (?, ?, ?, ?)", ws, createdID, owner, c.Text)
 		if err != nil {
 			tx.Rollback()
 			log.Println("comment inserting")
@@ -63,13 +94,27 @@ func (r *Repository) CreateItem(ctx context.Context, i item.Item) (int, error) {
 		}
 	}
 
+	// insert checklist
+	if err := addChecklist(ctx, tx, ws, createdID, i.Checklist); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
 	// insert labels
-	for _, l := range i.Labels {
-		_, err := tx.ExecContext(ctx, "INSERT INTO label(itemId, label) VALUES (?, ?)", createdID, l.Text)
-		if err != nil {
-			tx.Rollback()
-			return 0, err
-		}
+	if err := tagItem(ctx, tx, ws, createdID, i.Labels); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// record the created item
+	i.ID = createdID
+	if err := writeAudit(ctx, tx, audit.ActionItemCreated, createdID, nil, i); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := r.writeVersion(ctx, tx, createdID, nil); err != nil {
+		tx.Rollback()
+		return 0, err
 	}
 
 	// execute transaction
@@ -82,100 +127,401 @@ func (r *Repository) CreateItem(ctx context.Context, i item.Item) (int, error) {
 	return createdID, err
 }
 
-// GetItems returns list of all the items
-func (r *Repository) GetItems(ctx context.Context) ([]item.Item, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT id, title, description, status, due FROM item")
+// GetItems returns list of all the items matching the query
+func (r *Repository) GetItems(ctx context.Context, q item.Query) ([]item.Item, error) {
+	scope, scopeArgs, err := itemScope(ctx)
 	if err != nil {
 		return nil, err
 	}
+	filter, filterArgs := itemFilter(q)
+	return r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE "+scope+filter+itemOrder(q), append(scopeArgs, filterArgs...)...)
+}
 
-	labels, err := r.getAllLabels(ctx)
+// GetItem returns an item corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetItem(ctx context.Context, id int) (*item.Item, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE id=? AND "+scope+" LIMIT 1", withScope(scopeArgs, id)...)
 	if err != nil {
 		return nil, err
 	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// MoveItem assigns the item to the list, listID 0 removes the item from its list
+// The item keeps its state when the list has a state with the same name, otherwise it is put in the first state
+// matching its status, it is appended to the manual order of the list
+func (r *Repository) MoveItem(ctx context.Context, id int, listID int) error {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	before, err := r.lockItem(ctx, tx, id)
+	if err != nil || before == nil {
+		tx.Rollback()
+		return err
+	}
+	after := *before
+	after.ListID = listID
+	after.State = ""
+	state, err := itemState(ctx, tx, listID, before.State, &after, false)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	after.Position, err = lastPosition(ctx, tx, &after)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE item SET listId=?, stateId=?, status=?, position=?, completed=IF(?, COALESCE(completed, NOW()), NULL), updated=NOW() WHERE id=? AND "+scope, withScope(scopeArgs, nullInt(listID), state, after.Status, after.Position, after.Status, id)...)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionItemMoved, id, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := r.writeVersion(ctx, tx, id, before); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateItem replaces the title, description, status, state, due date, start date, estimate and labels of the item
+// The list, the comments and the snooze of the item are not changed, returns false if the item doesn't exist
+// Returns ErrTransition when the workflow of the list does not allow moving the item to its new state
+func (r *Repository) UpdateItem(ctx context.Context, i item.Item) (bool, error) {
+	return r.updateItem(ctx, i, audit.ActionItemUpdated)
+}
+
+// updateItem replaces the item fields like UpdateItem and records the change with the provided audit action
+func (r *Repository) updateItem(ctx context.Context, i item.Item, action string) (bool, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	before, err := r.lockItem(ctx, tx, i.ID)
+	if err != nil || before == nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// a restored version puts the item back in its state without following the workflow
+	state, err := itemState(ctx, tx, before.ListID, before.State, &i, action == audit.ActionItemUpdated)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	var due *time.Time
+	if !i.DueDate.IsZero() {
+		due = &i.DueDate
+	}
+	// the item waits for its new start date or for the end of its snooze
+	i.SnoozedUntil = before.SnoozedUntil
+	// completed keeps the time the item was first marked as done until it is reopened
+	_, err = tx.ExecContext(ctx, "UPDATE item SET title=?, description=?, status=?, stateId=?, due=?, start=?, deferred=?, estimate=?, completed=IF(?, COALESCE(completed, NOW()), NULL), updated=NOW() WHERE id=? AND "+scope, withScope(scopeArgs, i.Title, i.Description, i.Status, state, due, i.StartDate, i.Deferred(time.Now()), i.Estimate, i.Status, i.ID)...)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	// replace labels
+	if !sameLabels(before.Labels, i.Labels) {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM itemLabel WHERE itemId=?", i.ID); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if err := tagItem(ctx, tx, ws, i.ID, i.Labels); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if action == audit.ActionItemRestored {
+		if err := restoreComments(ctx, tx, ws, i.ID, before.Comments, i.Comments); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
 
-	comments, err := r.getAllComments(ctx)
+	after, err := r.lockItem(ctx, tx, i.ID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, action, i.ID, before, after); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := r.writeVersion(ctx, tx, i.ID, before); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// DeleteItem moves the item to the trash, returns false if the item doesn't exist
+// The item is kept with its labels and comments until it is restored or purged
+func (r *Repository) DeleteItem(ctx context.Context, id int) (bool, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	before, err := r.lockItem(ctx, tx, id)
+	if err != nil || before == nil {
+		tx.Rollback()
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE item SET deleted=NOW() WHERE id=? AND "+scope, withScope(scopeArgs, id)...); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionItemDeleted, id, before, nil); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// changeItem runs a change of the comments or of the checklist of the item in one transaction with the item row
+// locked and stores the new version of the item after the change, the change is rolled back when it fails
+// The change returns errNoChange when there is nothing to change so no version is stored
+func (r *Repository) changeItem(ctx context.Context, itemID int, change func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	i, err := r.lockItem(ctx, tx, itemID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if i == nil {
+		tx.Rollback()
+		return errNoItem
+	}
+	if err := change(tx); err != nil {
+		tx.Rollback()
+		if err == errNoChange {
+			return nil
+		}
+		return err
+	}
+	if err := r.writeVersion(ctx, tx, itemID, i); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// itemFilter returns the conditions of the query appended to the scope of the item listings
+// and their arguments
+func itemFilter(q item.Query) (string, []interface{}) {
+	filter := ""
+	args := []interface{}{}
+	if !q.IncludeArchived {
+		filter += " AND item.archived IS NULL"
+	}
+	if !q.IncludeDeferred {
+		filter += " AND (item.start IS NULL OR item.start<=NOW()) AND (item.snoozed IS NULL OR item.snoozed<=NOW())"
+	}
+	for _, l := range q.Labels {
+		const tagged = " AND item.id IN (SELECT il.itemId FROM itemLabel il JOIN label l ON l.id=il.labelId WHERE "
+		if strings.HasSuffix(l, item.Wildcard) {
+			prefix := strings.TrimSuffix(l, item.Wildcard)
+			filter += tagged + "(l.name=? OR l.name LIKE ?))"
+			args = append(args, prefix, escapeLike(prefix)+"/%")
+			continue
+		}
+		filter += tagged + "l.name=?)"
+		args = append(args, l)
+	}
+	return filter, args
+}
+
+// itemOrder returns the order of the item listings
+func itemOrder(q item.Query) string {
+	if q.Sort == item.SortPosition {
+		return " ORDER BY item.position, item.id"
+	}
+	return ""
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// itemColumns are the item columns read by getItems in the same order
+const itemColumns = "id, workspaceId, ownerId, listId, title, description, status, (SELECT s.name FROM workflowState s WHERE s.id=item.stateId), position, due, start, snoozed, estimate, completed, archived, deleted"
+
+// querier is implemented by both sql.DB and sql.Tx so the items can be read inside a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getItems returns the items selected by the query together with their labels and comments
+func (r *Repository) getItems(ctx context.Context, query string, args ...interface{}) ([]item.Item, error) {
+	return r.queryItems(ctx, r.db, query, args...)
+}
+
+// queryItems returns the items selected by the query on q together with their labels, comments and checklists
+func (r *Repository) queryItems(ctx context.Context, q querier, query string, args ...interface{}) ([]item.Item, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	items := []item.Item{}
+	ids := []int{}
 	for rows.Next() {
 		i := item.Item{}
+		listID := sql.NullInt64{}
+		state := sql.NullString{}
 		dueDate := mysql.NullTime{}
-		if err := rows.Scan(&i.ID, &i.Title, &i.Description, &i.Status, &dueDate); err != nil {
+		start := mysql.NullTime{}
+		snoozed := mysql.NullTime{}
+		completed := mysql.NullTime{}
+		archived := mysql.NullTime{}
+		deleted := mysql.NullTime{}
+		if err := rows.Scan(&i.ID, &i.WorkspaceID, &i.OwnerID, &listID, &i.Title, &i.Description, &i.Status, &state, &i.Position, &dueDate, &start, &snoozed, &i.Estimate, &completed, &archived, &deleted); err != nil {
 			return nil, err
 		}
-		i.Comments = comments[i.ID]
-		i.Labels = labels[i.ID]
+		i.ListID = int(listID.Int64)
+		i.State = state.String
 		if dueDate.Valid {
 			i.DueDate = dueDate.Time
 		}
+		if start.Valid {
+			i.StartDate = &start.Time
+		}
+		if snoozed.Valid {
+			i.SnoozedUntil = &snoozed.Time
+		}
+		if completed.Valid {
+			i.CompletedAt = &completed.Time
+		}
+		if archived.Valid {
+			i.ArchivedAt = &archived.Time
+		}
+		if deleted.Valid {
+			i.DeletedAt = &deleted.Time
+		}
 		items = append(items, i)
+		ids = append(ids, i.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
 	}
 
-	return items, nil
-}
+	labels, err := r.getLabelsByID(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
 
-// GetItem returns an item corresponding to the provided id and nil if it doesn't exist
-func (r *Repository) GetItem(ctx context.Context, id int) (*item.Item, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT id, title, description, status, due FROM item WHERE id=? LIMIT 1", id)
+	comments, err := r.getCommentsByID(ctx, q, ids)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	labels, err := r.getLabelsByID(ctx, []int{id})
+	checklists, err := r.getChecklistsByID(ctx, q, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	comments, err := r.getCommentsByID(ctx, []int{id})
+	blocked, err := r.getBlockedByID(ctx, q, ids)
 	if err != nil {
 		return nil, err
 	}
 
-	for rows.Next() {
-		i := &item.Item{}
-		dueDate := mysql.NullTime{}
-		if err := rows.Scan(&i.ID, &i.Title, &i.Description, &i.Status, &dueDate); err != nil {
-			return nil, err
-		}
-		i.Comments = comments[i.ID]
-		i.Labels = labels[i.ID]
-		if dueDate.Valid {
-			i.DueDate = dueDate.Time
+	tracked, err := r.getTrackedByID(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range items {
+		items[k].Comments = comments[items[k].ID]
+		items[k].Labels = labels[items[k].ID]
+		items[k].Checklist = checklists[items[k].ID]
+		items[k].Progress = item.ChecklistProgress(items[k].Checklist)
+		items[k].Blocked = blocked[items[k].ID]
+		items[k].Tracked = tracked[items[k].ID]
+	}
+
+	return items, nil
+}
+
+// sameLabels reports whether both label lists have the same texts in the same order
+func sameLabels(a, b []item.Label) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k].Text != b[k].Text {
+			return false
 		}
-		return i, nil
 	}
+	return true
+}
 
-	return nil, nil
+// nullInt converts the zero id to NULL
+func nullInt(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
 }
 
-func (r *Repository) getLabelsByID(ctx context.Context, itemIds []int) (map[int][]item.Label, error) {
+func (r *Repository) getLabelsByID(ctx context.Context, q querier, itemIds []int) (map[int][]item.Label, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	itemIdsStr := make([]string, len(itemIds))
 	for i, value := range itemIds {
 		itemIdsStr[i] = strconv.Itoa(value)
 	}
-	sqlStatement := fmt.Sprintf("SELECT id, itemId, label FROM label WHERE itemId IN(%s)", strings.Join(itemIdsStr, ", "))
-	return r.getLabels(ctx, sqlStatement)
+	sqlStatement := fmt.Sprintf("SELECT l.id, il.itemId, l.name, l.color FROM itemLabel il JOIN label l ON l.id=il.labelId WHERE l.workspaceId=? AND il.itemId IN(%s) ORDER BY il.itemId, il.position", strings.Join(itemIdsStr, ", "))
+	return r.getLabels(ctx, q, sqlStatement, ws)
 }
 
-func (r *Repository) getAllLabels(ctx context.Context) (map[int][]item.Label, error) {
-	sqlStatement := "SELECT id, itemId, label FROM label"
-	return r.getLabels(ctx, sqlStatement)
-}
-
-func (r *Repository) getLabels(ctx context.Context, sql string) (map[int][]item.Label, error) {
-	rows, err := r.db.QueryContext(ctx, sql)
+func (r *Repository) getLabels(ctx context.Context, q querier, sql string, args ...interface{}) (map[int][]item.Label, error) {
+	rows, err := q.QueryContext(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	labels := make(map[int][]item.Label)
 	for rows.Next() {
 		i := item.Label{}
-		if err := rows.Scan(&i.ID, &i.ItemID, &i.Text); err != nil {
+		if err := rows.Scan(&i.ID, &i.ItemID, &i.Text, &i.Color); err != nil {
 			return nil, err
 		}
 		labels[i.ItemID] = append(labels[i.ItemID], i)
@@ -184,30 +530,30 @@ func (r *Repository) getLabels(ctx context.Context, sql string) (map[int][]item.
 	return labels, nil
 }
 
-func (r *Repository) getCommentsByID(ctx context.Context, itemIds []int) (map[int][]item.Comment, error) {
+func (r *Repository) getCommentsByID(ctx context.Context, q querier, itemIds []int) (map[int][]item.Comment, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	itemIdsStr := make([]string, len(itemIds))
 	for i, value := range itemIds {
 		itemIdsStr[i] = strconv.Itoa(value)
 	}
-	sqlStatement := fmt.Sprintf("SELECT id, itemId, comment FROM comment WHERE itemId IN(%s)", strings.Join(itemIdsStr, ", "))
-	return r.getComments(ctx, sqlStatement)
+	sqlStatement := fmt.Sprintf(commentSelect+" WHERE c.workspaceId=? AND c.itemId IN(%s) ORDER BY c.id", strings.Join(itemIdsStr, ", "))
+	return r.getComments(ctx, q, sqlStatement, ws)
 }
 
-func (r *Repository) getAllComments(ctx context.Context) (map[int][]item.Comment, error) {
-	sqlStatement := "SELECT id, itemId, comment FROM comment"
-	return r.getComments(ctx, sqlStatement)
-}
-
-func (r *Repository) getComments(ctx context.Context, sql string) (map[int][]item.Comment, error) {
-	rows, err := r.db.QueryContext(ctx, sql)
+func (r *Repository) getComments(ctx context.Context, q querier, sql string, args ...interface{}) (map[int][]item.Comment, error) {
+	rows, err := q.QueryContext(ctx, sql, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	comments := make(map[int][]item.Comment)
 	for rows.Next() {
 		i := item.Comment{}
-		if err := rows.Scan(&i.ID, &i.ItemID, &i.Text); err != nil {
+		if err := rows.Scan(&i.ID, &i.ItemID, &i.AuthorID, &i.Author, &i.ParentID, &i.Text, &i.Edited, &i.CreatedAt, &i.UpdatedAt); err != nil {
 			return nil, err
 		}
 		comments[i.ItemID] = append(comments[i.ItemID], i)
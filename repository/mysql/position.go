@@ -0,0 +1,152 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/rank"
+)
+
+// PositionItem moves the item right before the other item in the manual order, or right after it
+// Only the rank of the item changes unless the ranks around the other item left no room between them
+// Returns false if one of the items doesn't exist or they are not ordered together
+func (r *Repository) PositionItem(ctx context.Context, id int, otherID int, after bool) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	before, err := r.lockItem(ctx, tx, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	other, err := r.lockItem(ctx, tx, otherID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if before == nil || other == nil || id == otherID || before.ListID != other.ListID || (before.ListID == 0 && before.OwnerID != other.OwnerID) {
+		tx.Rollback()
+		return false, nil
+	}
+
+	scope, scopeArgs, err := rankScope(ctx, before)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	position := ""
+	if other.Position != "" {
+		if position, err = positionNextTo(ctx, tx, scope, scopeArgs, id, other.Position, after); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+	if position == "" || len(position) > rank.MaxLength {
+		// the ranks are spread again, the rank of the other item changed
+		if err := spreadPositions(ctx, tx, scope, scopeArgs); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if err := tx.QueryRowContext(ctx, "SELECT position FROM item WHERE id=?", otherID).Scan(&other.Position); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if position, err = positionNextTo(ctx, tx, scope, scopeArgs, id, other.Position, after); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE item SET position=?, updated=NOW() WHERE id=?", position, id); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	moved := *before
+	moved.Position = position
+	if err := writeAudit(ctx, tx, audit.ActionItemPositioned, id, before, moved); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// rankScope returns the condition restricting the item table to the items ordered together with the item,
+// the items of its list or the own items of its owner without a list
+func rankScope(ctx context.Context, i *item.Item) (string, []interface{}, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if i.ListID != 0 {
+		return "listId=? AND workspaceId=?", []interface{}{i.ListID, ws}, nil
+	}
+	return "listId IS NULL AND ownerId=? AND workspaceId=?", []interface{}{i.OwnerID, ws}, nil
+}
+
+// lastPosition returns a rank after all the ranks of the items ordered together with the item
+// The ranks are locked until the end of the transaction so two new items do not get the same one
+func lastPosition(ctx context.Context, tx *sql.Tx, i *item.Item) (string, error) {
+	scope, scopeArgs, err := rankScope(ctx, i)
+	if err != nil {
+		return "", err
+	}
+	var last string
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(position), '') FROM item WHERE "+scope+" FOR UPDATE", scopeArgs...).Scan(&last); err != nil {
+		return "", err
+	}
+	return rank.After(last), nil
+}
+
+// positionNextTo returns a rank between the position and the closest rank before it, or after it
+// The rank of the item itself is left out, an empty rank means that there is no room left
+func positionNextTo(ctx context.Context, tx *sql.Tx, scope string, scopeArgs []interface{}, id int, position string, after bool) (string, error) {
+	query := "SELECT position FROM item WHERE id<>? AND position<? AND " + scope + " ORDER BY position DESC LIMIT 1"
+	if after {
+		query = "SELECT position FROM item WHERE id<>? AND position>? AND " + scope + " ORDER BY position LIMIT 1"
+	}
+	var next string
+	err := tx.QueryRowContext(ctx, query, withScope(scopeArgs, id, position)...).Scan(&next)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	if !after {
+		return rank.Between(next, position), nil
+	}
+	if next == "" {
+		return rank.After(position), nil
+	}
+	return rank.Between(position, next), nil
+}
+
+// spreadPositions gives new short ranks to the items ordered together in their current order
+func spreadPositions(ctx context.Context, tx *sql.Tx, scope string, scopeArgs []interface{}) error {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM item WHERE "+scope+" ORDER BY position, id FOR UPDATE", scopeArgs...)
+	if err != nil {
+		return err
+	}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	position := ""
+	for _, id := range ids {
+		position = rank.After(position)
+		if _, err := tx.ExecContext(ctx, "UPDATE item SET position=? WHERE id=?", position, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/workspace"
+)
+
+var errNoWorkspace = errors.New("mysql: no workspace in the context")
+
+// workspaceID returns the id of the workspace every query is restricted to
+// Data of other workspaces is never read nor written
+func workspaceID(ctx context.Context) (int, error) {
+	w, ok := workspace.FromContext(ctx)
+	if !ok {
+		return 0, errNoWorkspace
+	}
+	return w.ID, nil
+}
+
+// itemScope returns the condition restricting the item table to the items visible to the authenticated user
+// in the workspace of the request: its own items and the items of the lists it owns or which are shared with it
+// Items in the trash are excluded, see trashScope
+func itemScope(ctx context.Context) (string, []interface{}, error) {
+	scope, args, err := visibleScope(ctx)
+	return scope + " AND item.deleted IS NULL", args, err
+}
+
+// trashScope returns the condition restricting the item table to the deleted items visible to the authenticated user
+func trashScope(ctx context.Context) (string, []interface{}, error) {
+	scope, args, err := visibleScope(ctx)
+	return scope + " AND item.deleted IS NOT NULL", args, err
+}
+
+// visibleScope returns the condition of itemScope including the items in the trash
+func visibleScope(ctx context.Context) (string, []interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return "item.workspaceId=? AND (item.ownerId=? OR item.listId IN (SELECT id FROM list WHERE ownerId=?) OR item.listId IN (SELECT listId FROM listMember WHERE userId=?))",
+		[]interface{}{ws, owner, owner, owner}, nil
+}
+
+// withScope prepends the query arguments to the arguments of a scope
+func withScope(scope []interface{}, queryArgs ...interface{}) []interface{} {
+	return append(queryArgs, scope...)
+}
+
+// editScope returns the condition restricting the item table to the items the authenticated user can change:
+// its own items without a list and the items of the lists it owns or edits, items in the trash are excluded
+func editScope(ctx context.Context) (string, []interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return "item.workspaceId=? AND item.deleted IS NULL AND ((item.listId IS NULL AND item.ownerId=?) OR item.listId IN (SELECT id FROM list WHERE ownerId=?) OR item.listId IN (SELECT listId FROM listMember WHERE userId=? AND role=?))",
+		[]interface{}{ws, owner, owner, owner, list.RoleEditor}, nil
+}
@@ -0,0 +1,157 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/share"
+	"github.com/aflog/todolist/workspace"
+	"github.com/go-sql-driver/mysql"
+)
+
+const shareColumns = "id, workspaceId, createdBy, itemId, listId, hash, expires, revoked, accessCount, lastAccessed, created"
+
+// shareScope returns the condition restricting the share table to the links created by the authenticated user
+// in the workspace of the request
+func shareScope(ctx context.Context) (string, []interface{}, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return "createdBy=? AND workspaceId=?", []interface{}{owner, ws}, nil
+}
+
+// CreateShare stores provided share link created by the authenticated user and returns its id
+func (r *Repository) CreateShare(ctx context.Context, s share.Share) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.db.ExecContext(ctx, "INSERT INTO share(workspaceId, createdBy, itemId, listId, hash, expires) VALUES (?, ?, ?, ?, ?, ?)", ws, owner, nullInt(s.ItemID), nullInt(s.ListID), s.Hash, s.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetShares returns all the share links created by the authenticated user including the revoked ones
+func (r *Repository) GetShares(ctx context.Context) ([]share.Share, error) {
+	scope, scopeArgs, err := shareScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getShares(ctx, "SELECT "+shareColumns+" FROM share WHERE "+scope+" ORDER BY id", scopeArgs...)
+}
+
+// RevokeShare disables the share link, returns false if it doesn't exist
+func (r *Repository) RevokeShare(ctx context.Context, id int) (bool, error) {
+	scope, scopeArgs, err := shareScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, "UPDATE share SET revoked=COALESCE(revoked, NOW()) WHERE id=? AND "+scope, withScope(scopeArgs, id)...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetShareByHash returns the share link with the provided token hash and nil if it doesn't exist
+// It is not scoped as it is used by anonymous requests
+func (r *Repository) GetShareByHash(ctx context.Context, hash string) (*share.Share, error) {
+	shares, err := r.getShares(ctx, "SELECT "+shareColumns+" FROM share WHERE hash=? LIMIT 1", hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	return &shares[0], nil
+}
+
+// CountShareAccess records one access through the share link
+func (r *Repository) CountShareAccess(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE share SET accessCount=accessCount+1, lastAccessed=NOW() WHERE id=?", id)
+	return err
+}
+
+// GetSharedItem returns the item of the share link and nil if it doesn't exist anymore
+// The item is read in the workspace of the share link regardless of the context
+func (r *Repository) GetSharedItem(ctx context.Context, s share.Share) (*item.Item, error) {
+	ctx = sharedContext(ctx, s)
+	items, err := r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE id=? AND workspaceId=? AND deleted IS NULL LIMIT 1", s.ItemID, s.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// GetSharedList returns the list of the share link with its items and nil if it doesn't exist anymore
+// The list is read in the workspace of the share link regardless of the context
+func (r *Repository) GetSharedList(ctx context.Context, s share.Share) (*list.List, []item.Item, error) {
+	ctx = sharedContext(ctx, s)
+	lists, err := r.getLists(ctx, "SELECT id, ownerId, name, description, color, archived, 'viewer' FROM list WHERE id=? AND workspaceId=? LIMIT 1", s.ListID, s.WorkspaceID)
+	if err != nil || len(lists) == 0 {
+		return nil, nil, err
+	}
+	items, err := r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE listId=? AND workspaceId=? AND deleted IS NULL AND archived IS NULL", s.ListID, s.WorkspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &lists[0], items, nil
+}
+
+// sharedContext returns the context restricted to the workspace of the share link
+// so the labels and comments of the shared items are read from the same workspace
+func sharedContext(ctx context.Context, s share.Share) context.Context {
+	return workspace.NewContext(ctx, &workspace.Workspace{ID: s.WorkspaceID})
+}
+
+func (r *Repository) getShares(ctx context.Context, query string, args ...interface{}) ([]share.Share, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []share.Share{}
+	for rows.Next() {
+		s := share.Share{}
+		itemID := sql.NullInt64{}
+		listID := sql.NullInt64{}
+		revoked := mysql.NullTime{}
+		lastAccessed := mysql.NullTime{}
+		if err := rows.Scan(&s.ID, &s.WorkspaceID, &s.CreatedBy, &itemID, &listID, &s.Hash, &s.ExpiresAt, &revoked, &s.AccessCount, &lastAccessed, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.ItemID = int(itemID.Int64)
+		s.ListID = int(listID.Int64)
+		if revoked.Valid {
+			s.RevokedAt = &revoked.Time
+		}
+		if lastAccessed.Valid {
+			s.LastAccessedAt = &lastAccessed.Time
+		}
+		shares = append(shares, s)
+	}
+
+	return shares, rows.Err()
+}
@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/workspace"
+)
+
+// SnoozeItem leaves the item out of the listings until the provided time, nil ends the snooze
+// Returns false if the item doesn't exist
+func (r *Repository) SnoozeItem(ctx context.Context, id int, until *time.Time) (bool, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	before, err := r.lockItem(ctx, tx, id)
+	if err != nil || before == nil {
+		tx.Rollback()
+		return false, err
+	}
+	after := *before
+	after.SnoozedUntil = until
+	_, err = tx.ExecContext(ctx, "UPDATE item SET snoozed=?, deferred=?, updated=NOW() WHERE id=? AND "+scope, withScope(scopeArgs, until, after.Deferred(time.Now()), id)...)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionItemSnoozed, id, before, after); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ResurfaceItems puts back in the listings the deferred items whose start date and snooze passed at the provided
+// time in all the workspaces and returns them, the snooze of the items ends
+// It is not scoped as it is run by the resurface job, the items locked by another transaction are left for the next run
+func (r *Repository) ResurfaceItems(ctx context.Context, now time.Time) ([]item.Item, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT id, workspaceId FROM item WHERE deferred=true AND (start IS NULL OR start<=?) AND (snoozed IS NULL OR snoozed<=?) AND deleted IS NULL FOR UPDATE SKIP LOCKED", now, now)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// the labels, comments and checklists of the items are read in the workspace of the items
+	due := make(map[int][]string)
+	for rows.Next() {
+		var id, ws int
+		if err := rows.Scan(&id, &ws); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		due[ws] = append(due[ws], strconv.Itoa(id))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	items := []item.Item{}
+	for ws, ids := range due {
+		wsCtx := workspace.NewContext(ctx, &workspace.Workspace{ID: ws})
+		resurfaced, err := r.queryItems(wsCtx, tx, "SELECT "+itemColumns+" FROM item WHERE id IN("+strings.Join(ids, ", ")+")")
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		items = append(items, resurfaced...)
+	}
+	for k := range items {
+		if _, err := tx.ExecContext(ctx, "UPDATE item SET snoozed=NULL, deferred=false WHERE id=?", items[k].ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		items[k].SnoozedUntil = nil
+	}
+	return items, tx.Commit()
+}
@@ -0,0 +1,328 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/go-sql-driver/mysql"
+)
+
+// trackedTime is the duration of a time entry, the running timers count until now
+const trackedTime = "COALESCE(t.duration, TIMESTAMPDIFF(SECOND, t.started, NOW()))"
+
+// timeEntrySelect selects the time entries with the usernames of their users
+const timeEntrySelect = "SELECT t.id, t.itemId, t.userId, u.username, t.started, t.ended, " + trackedTime + ", t.note FROM timeEntry t JOIN user u ON u.id=t.userId"
+
+// GetTimeEntries returns the time tracked on the item by all the users from the oldest entry
+func (r *Repository) GetTimeEntries(ctx context.Context, itemID int) ([]item.TimeEntry, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getTimeEntries(ctx, r.db, timeEntrySelect+" WHERE t.itemId=? AND t.workspaceId=? ORDER BY t.started, t.id", itemID, ws)
+}
+
+// AddTimeEntry records the time the user spent on the item and returns its id
+func (r *Repository) AddTimeEntry(ctx context.Context, e item.TimeEntry) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = r.changeItem(ctx, e.ItemID, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "INSERT INTO timeEntry(workspaceId, itemId, userId, started, ended, duration, note) VALUES (?, ?, ?, ?, ?, ?, ?)", ws, e.ItemID, owner, e.StartedAt, e.EndedAt, e.Duration, e.Note)
+		if err != nil {
+			return err
+		}
+		if id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		e.ID, e.UserID = int(id), owner
+		return writeAudit(ctx, tx, audit.ActionTimeAdded, e.ItemID, nil, timeEntryAudit(e))
+	})
+	return int(id), err
+}
+
+// StartTimer starts a timer of the user on the item and returns it
+// A user has one running timer at a time in a workspace, the timer running on another item is stopped
+func (r *Repository) StartTimer(ctx context.Context, itemID int) (*item.TimeEntry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	running, err := r.lockTimers(ctx, tx, owner, timeEntrySelect+" WHERE t.userId=? AND t.workspaceId=? AND t.ended IS NULL", owner, ws)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	ids := []int{itemID}
+	for _, e := range running {
+		ids = append(ids, e.ItemID)
+	}
+	items, err := r.lockItems(ctx, tx, ids)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if items[itemID] == nil {
+		tx.Rollback()
+		return nil, errNoItem
+	}
+
+	for _, e := range running {
+		if _, err := r.stopTimer(ctx, tx, e); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		// the other item gets a new version as well, unless the user can not see it anymore
+		if e.ItemID != itemID && items[e.ItemID] != nil {
+			if err := r.writeVersion(ctx, tx, e.ItemID, items[e.ItemID]); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO timeEntry(workspaceId, itemId, userId, started) VALUES (?, ?, ?, NOW())", ws, itemID, owner)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	entries, err := r.getTimeEntries(ctx, tx, timeEntrySelect+" WHERE t.id=?", id)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionTimeStarted, itemID, nil, timeEntryAudit(entries[0])); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := r.writeVersion(ctx, tx, itemID, items[itemID]); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return &entries[0], tx.Commit()
+}
+
+// StopTimer stops the timer of the user running on the item and returns it, nil if there is no such timer
+func (r *Repository) StopTimer(ctx context.Context, itemID int) (*item.TimeEntry, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	running, err := r.lockTimers(ctx, tx, owner, timeEntrySelect+" WHERE t.userId=? AND t.itemId=? AND t.ended IS NULL", owner, itemID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	i, err := r.lockItem(ctx, tx, itemID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if i == nil {
+		tx.Rollback()
+		return nil, errNoItem
+	}
+	if len(running) == 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+	stopped, err := r.stopTimer(ctx, tx, running[0])
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := r.writeVersion(ctx, tx, itemID, i); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return stopped, tx.Commit()
+}
+
+// lockTimers locks the timers of the user and returns the entries of the query
+// The user row is locked before the items so the timers of a user are started and stopped one at a time
+// without two transactions locking the same items in a different order
+func (r *Repository) lockTimers(ctx context.Context, tx *sql.Tx, userID int, query string, args ...interface{}) ([]item.TimeEntry, error) {
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM user WHERE id=? FOR UPDATE", userID); err != nil {
+		return nil, err
+	}
+	return r.getTimeEntries(ctx, tx, query, args...)
+}
+
+// lockItems locks the items visible to the user in the order of their ids and returns them by id,
+// the items which don't exist are nil
+func (r *Repository) lockItems(ctx context.Context, tx *sql.Tx, ids []int) (map[int]*item.Item, error) {
+	sort.Ints(ids)
+	items := make(map[int]*item.Item)
+	for _, id := range ids {
+		if _, ok := items[id]; ok {
+			continue
+		}
+		i, err := r.lockItem(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		items[id] = i
+	}
+	return items, nil
+}
+
+// DeleteTimeEntry removes a time entry of the user from the item, returns false if there is no such entry
+func (r *Repository) DeleteTimeEntry(ctx context.Context, itemID int, id int) (bool, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	err = r.changeItem(ctx, itemID, func(tx *sql.Tx) error {
+		entries, err := r.getTimeEntries(ctx, tx, timeEntrySelect+" WHERE t.id=? AND t.itemId=? AND t.userId=? FOR UPDATE OF t", id, itemID, owner)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return errNoChange
+		}
+		found = true
+		if _, err := tx.ExecContext(ctx, "DELETE FROM timeEntry WHERE id=?", id); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, audit.ActionTimeDeleted, itemID, timeEntryAudit(entries[0]), nil)
+	})
+	return found, err
+}
+
+// stopTimer ends the running timer now and records it in the audit log of its item, returns the stopped entry
+func (r *Repository) stopTimer(ctx context.Context, tx *sql.Tx, running item.TimeEntry) (*item.TimeEntry, error) {
+	if _, err := tx.ExecContext(ctx, "UPDATE timeEntry SET ended=NOW(), duration=TIMESTAMPDIFF(SECOND, started, NOW()) WHERE id=?", running.ID); err != nil {
+		return nil, err
+	}
+	entries, err := r.getTimeEntries(ctx, tx, timeEntrySelect+" WHERE t.id=?", running.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionTimeStopped, running.ItemID, timeEntryAudit(running), timeEntryAudit(entries[0])); err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// timeEntryAudit returns the fields of the time entry recorded in the audit log, a running timer has no duration
+func timeEntryAudit(e item.TimeEntry) interface{} {
+	duration := e.Duration
+	if e.EndedAt == nil {
+		duration = 0
+	}
+	return struct {
+		ID       int    `json:"id"`
+		UserID   int    `json:"userId"`
+		Duration int    `json:"duration"`
+		Note     string `json:"note"`
+	}{e.ID, e.UserID, duration, e.Note}
+}
+
+// GetTimeReport sums the time tracked from the provided time until the other one on the items visible to the user
+// by label together with the estimates of the items, the entries are counted when they started in the period
+func (r *Repository) GetTimeReport(ctx context.Context, from time.Time, to time.Time) ([]item.TimeSummary, error) {
+	scope, scopeArgs, err := itemScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT COALESCE(l.name, ''), COUNT(*), SUM(tracked.seconds), SUM(item.estimate)
+		FROM (SELECT t.itemId, SUM(`+trackedTime+`) AS seconds FROM timeEntry t
+			WHERE t.workspaceId=? AND t.started>=? AND t.started<? GROUP BY t.itemId) tracked
+		JOIN item ON item.id=tracked.itemId
+		LEFT JOIN itemLabel il ON il.itemId=item.id LEFT JOIN label l ON l.id=il.labelId
+		WHERE `+scope+` GROUP BY l.name ORDER BY l.name`, withScope(scopeArgs, ws, from, to)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := []item.TimeSummary{}
+	for rows.Next() {
+		var s item.TimeSummary
+		if err := rows.Scan(&s.Label, &s.Items, &s.Tracked, &s.Estimated); err != nil {
+			return nil, err
+		}
+		report = append(report, s)
+	}
+	return report, rows.Err()
+}
+
+// getTrackedByID returns the time tracked on the items by all the users, in seconds
+func (r *Repository) getTrackedByID(ctx context.Context, q querier, itemIds []int) (map[int]int, error) {
+	itemIdsStr := make([]string, len(itemIds))
+	for i, value := range itemIds {
+		itemIdsStr[i] = strconv.Itoa(value)
+	}
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT t.itemId, SUM(%s) FROM timeEntry t WHERE t.itemId IN(%s) GROUP BY t.itemId", trackedTime, strings.Join(itemIdsStr, ", ")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracked := make(map[int]int)
+	for rows.Next() {
+		var id, seconds int
+		if err := rows.Scan(&id, &seconds); err != nil {
+			return nil, err
+		}
+		tracked[id] = seconds
+	}
+	return tracked, rows.Err()
+}
+
+func (r *Repository) getTimeEntries(ctx context.Context, q querier, query string, args ...interface{}) ([]item.TimeEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []item.TimeEntry{}
+	for rows.Next() {
+		var e item.TimeEntry
+		ended := mysql.NullTime{}
+		if err := rows.Scan(&e.ID, &e.ItemID, &e.UserID, &e.User, &e.StartedAt, &ended, &e.Duration, &e.Note); err != nil {
+			return nil, err
+		}
+		if ended.Valid {
+			e.EndedAt = &ended.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,76 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+)
+
+// GetTrash returns the deleted items visible to the authenticated user from the most recently deleted one
+func (r *Repository) GetTrash(ctx context.Context) ([]item.Item, error) {
+	scope, scopeArgs, err := trashScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE "+scope+" ORDER BY item.deleted DESC", scopeArgs...)
+}
+
+// GetDeletedItem returns the deleted item corresponding to the provided id and nil if it isn't in the trash
+func (r *Repository) GetDeletedItem(ctx context.Context, id int) (*item.Item, error) {
+	scope, scopeArgs, err := trashScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.getItems(ctx, "SELECT "+itemColumns+" FROM item WHERE id=? AND "+scope+" LIMIT 1", withScope(scopeArgs, id)...)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// RestoreItem takes the item out of the trash, returns false if the item isn't in the trash
+func (r *Repository) RestoreItem(ctx context.Context, id int) (bool, error) {
+	scope, scopeArgs, err := trashScope(ctx)
+	if err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx, "UPDATE item SET deleted=NULL WHERE id=? AND "+scope, withScope(scopeArgs, id)...)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return false, err
+	}
+	after, err := r.lockItem(ctx, tx, id)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := writeAudit(ctx, tx, audit.ActionItemUndeleted, id, nil, after); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := r.writeVersion(ctx, tx, id, nil); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// PurgeDeletedItems permanently removes the items deleted before the provided time in all the workspaces
+// and returns the number of removed items, it is not scoped as it is run by the retention job
+func (r *Repository) PurgeDeletedItems(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM item WHERE deleted IS NOT NULL AND deleted<?", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
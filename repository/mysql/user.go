@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/user"
+	"github.com/aflog/todolist/workspace"
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysql error number of a duplicate entry for a unique key
+const errDuplicateEntry = 1062
+
+var errNoUser = errors.New("mysql: no authenticated user in the context")
+
+// CreateUser stores provided user and returns its id
+// The user becomes a member of the default workspace in the same transaction
+// Returns repository.ErrConflict if the username or the email is already taken
+func (r *Repository) CreateUser(ctx context.Context, u user.User) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO user(username, email, password) VALUES (?, ?, ?)", u.Username, u.Email, u.PasswordHash)
+	if err != nil {
+		tx.Rollback()
+		if isDuplicate(err) {
+			return 0, repository.ErrConflict
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO workspaceMember(workspaceId, userId, role) SELECT id, ?, ? FROM workspace WHERE slug=?", id, workspace.RoleMember, workspace.DefaultSlug)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetUser returns a user corresponding to the provided id and nil if it doesn't exist
+func (r *Repository) GetUser(ctx context.Context, id int) (*user.User, error) {
+	return r.getUser(ctx, "SELECT id, username, email, password FROM user WHERE id=? LIMIT 1", id)
+}
+
+// GetUserByUsername returns a user corresponding to the provided username and nil if it doesn't exist
+func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*user.User, error) {
+	return r.getUser(ctx, "SELECT id, username, email, password FROM user WHERE username=? LIMIT 1", username)
+}
+
+func (r *Repository) getUser(ctx context.Context, query string, args ...interface{}) (*user.User, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u := &user.User{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash); err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+
+	return nil, rows.Err()
+}
+
+// ownerID returns the id of the authenticated user the queries are scoped to
+func ownerID(ctx context.Context) (int, error) {
+	u, ok := user.FromContext(ctx)
+	if !ok {
+		return 0, errNoUser
+	}
+	return u.ID, nil
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+)
+
+// versionSelect reads the item versions together with the username of the actor
+const versionSelect = "SELECT v.version, v.actorId, COALESCE(u.username, ''), v.snapshot, v.created FROM itemVersion v LEFT JOIN user u ON u.id=v.actorId"
+
+// writeVersion stores the current state of the item read inside the transaction as its next version
+// Items changed before the versions existed get the state before the change as their first version
+func (r *Repository) writeVersion(ctx context.Context, tx *sql.Tx, itemID int, before *item.Item) error {
+	actor, err := ownerID(ctx)
+	if err != nil {
+		return err
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	var last int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM itemVersion WHERE itemId=? AND workspaceId=?", itemID, ws).Scan(&last); err != nil {
+		return err
+	}
+
+	snapshots := []*item.Item{}
+	if last == 0 && before != nil {
+		snapshots = append(snapshots, before)
+	}
+	current, err := r.lockItem(ctx, tx, itemID)
+	if err != nil {
+		return err
+	}
+	snapshots = append(snapshots, current)
+
+	for _, i := range snapshots {
+		last++
+		b, err := json.Marshal(i)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO itemVersion(workspaceId, itemId, version, actorId, snapshot) VALUES (?, ?, ?, ?, ?)", ws, itemID, last, actor, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetItemVersions returns the versions of the item from the oldest one
+func (r *Repository) GetItemVersions(ctx context.Context, itemID int) ([]item.Version, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getVersions(ctx, versionSelect+" WHERE v.itemId=? AND v.workspaceId=? ORDER BY v.version", itemID, ws)
+}
+
+// GetItemVersion returns the version n of the item and nil if it doesn't exist
+func (r *Repository) GetItemVersion(ctx context.Context, itemID int, n int) (*item.Version, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := r.getVersions(ctx, versionSelect+" WHERE v.itemId=? AND v.workspaceId=? AND v.version=?", itemID, ws, n)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	return &versions[0], nil
+}
+
+// RestoreItemVersion replaces the title, description, status, due date, labels and comments of the item with the
+// ones of the version n, the restore is recorded as a new version
+// Returns false if the item or the version doesn't exist
+func (r *Repository) RestoreItemVersion(ctx context.Context, itemID int, n int) (bool, error) {
+	v, err := r.GetItemVersion(ctx, itemID, n)
+	if err != nil || v == nil {
+		return false, err
+	}
+	i := v.Item
+	i.ID = itemID
+	return r.updateItem(ctx, i, audit.ActionItemRestored)
+}
+
+// restoreComments puts back the comments of a restored version: the comments added since are removed, the removed
+// ones are inserted again with their ids and the edited ones get back their text, keeping it in their edit history
+// The comments are ordered by id so a comment is restored before its replies
+func restoreComments(ctx context.Context, tx *sql.Tx, ws int, itemID int, current []item.Comment, restored []item.Comment) error {
+	kept := make(map[int]bool)
+	for _, c := range restored {
+		kept[c.ID] = true
+	}
+	existing := make(map[int]item.Comment)
+	for _, c := range current {
+		if !kept[c.ID] {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM comment WHERE id=?", c.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		existing[c.ID] = c
+	}
+
+	for _, c := range restored {
+		e, ok := existing[c.ID]
+		if ok && e.Text == c.Text {
+			continue
+		}
+		if ok {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO commentEdit(workspaceId, commentId, comment) VALUES (?, ?, ?)", ws, c.ID, e.Text); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE comment SET comment=?, edited=true, updated=NOW() WHERE id=?", c.Text, c.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		// the author may have been removed since
+		_, err := tx.ExecContext(ctx, `INSERT INTO comment(id, workspaceId, itemId, authorId, parentId, comment, edited, created, updated)
+			VALUES (?, ?, ?, (SELECT id FROM user WHERE id=?), ?, ?, ?, ?, ?)`,
+			c.ID, ws, itemID, c.AuthorID, nullInt(c.ParentID), c.Text, c.Edited, c.CreatedAt, c.UpdatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) getVersions(ctx context.Context, query string, args ...interface{}) ([]item.Version, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []item.Version{}
+	for rows.Next() {
+		v := item.Version{}
+		var snapshot []byte
+		if err := rows.Scan(&v.Number, &v.ActorID, &v.Actor, &snapshot, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot, &v.Item); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
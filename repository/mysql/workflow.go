@@ -0,0 +1,225 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/aflog/todolist/audit"
+	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/repository"
+)
+
+// GetWorkflow returns the states of the list in their order and the transitions allowed between them
+func (r *Repository) GetWorkflow(ctx context.Context, listID int) (*list.Workflow, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getWorkflow(ctx, r.db, ws, listID)
+}
+
+// UpdateWorkflow replaces the workflow of the list, the states are matched by name so the items keep their state
+// Returns ErrStateInUse when a removed state still holds items, the items follow the done flag of their state
+// and the ids of the items whose status changed are returned, the items in the trash are left out
+func (r *Repository) UpdateWorkflow(ctx context.Context, listID int, w list.Workflow) ([]int, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the workflow of a list is changed one at a time
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM list WHERE id=? AND workspaceId=? FOR UPDATE", listID, ws); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	current, err := getWorkflow(ctx, tx, ws, listID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	ids := make(map[string]int)
+	for _, s := range current.States {
+		if w.State(s.Name) != nil {
+			ids[s.Name] = s.ID
+			continue
+		}
+		// the deleted items keep their state as well so they can be restored in it
+		var used bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM item WHERE stateId=?)", s.ID).Scan(&used); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if used {
+			tx.Rollback()
+			return nil, repository.ErrStateInUse
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM workflowState WHERE id=?", s.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM workflowTransition WHERE listId=?", listID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := saveWorkflow(ctx, tx, ws, listID, w, ids); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT item.id, item.status, item.deleted IS NOT NULL FROM item JOIN workflowState s ON s.id=item.stateId
+		WHERE s.listId=? AND item.status<>s.done ORDER BY item.id FOR UPDATE`, listID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	type change struct {
+		id      int
+		status  bool
+		deleted bool
+	}
+	changes := []change{}
+	for rows.Next() {
+		var c change
+		if err := rows.Scan(&c.id, &c.status, &c.deleted); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	changed := []int{}
+	for _, c := range changes {
+		// completed keeps the time the item was first marked as done until it is reopened
+		_, err = tx.ExecContext(ctx, "UPDATE item SET status=?, completed=IF(?, COALESCE(completed, NOW()), NULL), updated=NOW() WHERE id=?", !c.status, !c.status, c.id)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := writeAudit(ctx, tx, audit.ActionItemUpdated, c.id, map[string]bool{"status": c.status}, map[string]bool{"status": !c.status}); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !c.deleted {
+			changed = append(changed, c.id)
+		}
+	}
+	return changed, tx.Commit()
+}
+
+// saveWorkflow stores the order and the done flag of the states of the workflow and its transitions
+// ids holds the ids of the states already stored by name, the other states are inserted
+func saveWorkflow(ctx context.Context, tx *sql.Tx, ws int, listID int, w list.Workflow, ids map[string]int) error {
+	for k, s := range w.States {
+		if id, ok := ids[s.Name]; ok {
+			if _, err := tx.ExecContext(ctx, "UPDATE workflowState SET position=?, done=? WHERE id=?", k, s.Done, id); err != nil {
+				return err
+			}
+			continue
+		}
+		res, err := tx.ExecContext(ctx, "INSERT INTO workflowState(workspaceId, listId, name, position, done) VALUES (?, ?, ?, ?, ?)", ws, listID, s.Name, k, s.Done)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids[s.Name] = int(id)
+	}
+	for _, t := range w.Transitions {
+		if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO workflowTransition(listId, fromId, toId) VALUES (?, ?, ?)", listID, ids[t.From], ids[t.To]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemState puts the item i in a state of the workflow of the list and sets its status to the done flag
+// of the state, from is the state the item is in and the transition from it is only checked when transition is set
+// Without a new state the item stays in its state unless its status changed, it is then put in the first state
+// matching its status, the same happens to an unknown state when the transition is not checked
+// The items without a list and the ones of a list without a workflow have no state
+func itemState(ctx context.Context, tx *sql.Tx, listID int, from string, i *item.Item, transition bool) (sql.NullInt64, error) {
+	if listID == 0 {
+		if i.State != "" {
+			return sql.NullInt64{}, repository.ErrUnknownState
+		}
+		return sql.NullInt64{}, nil
+	}
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	w, err := getWorkflow(ctx, tx, ws, listID)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	if len(w.States) == 0 {
+		i.State = ""
+		return sql.NullInt64{}, nil
+	}
+
+	if i.State == "" || i.State == from || (!transition && w.State(i.State) == nil) {
+		i.State = from
+		if s := w.State(from); s == nil || s.Done != i.Status {
+			if s := w.Initial(i.Status); s != nil {
+				i.State = s.Name
+			}
+		}
+	} else if transition && from != "" && !w.Allows(from, i.State) {
+		return sql.NullInt64{}, repository.ErrTransition
+	}
+	s := w.State(i.State)
+	if s == nil {
+		return sql.NullInt64{}, repository.ErrUnknownState
+	}
+	i.Status = s.Done
+	return sql.NullInt64{Int64: int64(s.ID), Valid: true}, nil
+}
+
+func getWorkflow(ctx context.Context, q querier, ws int, listID int) (*list.Workflow, error) {
+	w := &list.Workflow{States: []list.State{}, Transitions: []list.Transition{}}
+	rows, err := q.QueryContext(ctx, "SELECT id, name, done FROM workflowState WHERE listId=? AND workspaceId=? ORDER BY position", listID, ws)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s list.State
+		if err := rows.Scan(&s.ID, &s.Name, &s.Done); err != nil {
+			return nil, err
+		}
+		w.States = append(w.States, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `SELECT f.name, t.name FROM workflowTransition x
+		JOIN workflowState f ON f.id=x.fromId JOIN workflowState t ON t.id=x.toId
+		WHERE x.listId=? AND f.workspaceId=? ORDER BY f.position, t.position`, listID, ws)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t list.Transition
+		if err := rows.Scan(&t.From, &t.To); err != nil {
+			return nil, err
+		}
+		w.Transitions = append(w.Transitions, t)
+	}
+	return w, rows.Err()
+}
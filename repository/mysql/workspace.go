@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/aflog/todolist/repository"
+	"github.com/aflog/todolist/workspace"
+)
+
+// workspaceSelect selects the workspaces together with the role of the user in them
+// It takes the user id as the first argument, the role is empty for workspaces the user is not a member of
+const workspaceSelect = `SELECT w.id, w.slug, w.name, COALESCE(m.role, '') FROM workspace w
+	LEFT JOIN workspaceMember m ON m.workspaceId=w.id AND m.userId=?`
+
+// CreateWorkspace stores provided workspace with the authenticated user as its admin and returns its id
+// Returns repository.ErrConflict if the slug is already taken
+func (r *Repository) CreateWorkspace(ctx context.Context, w workspace.Workspace) (int, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO workspace(slug, name) VALUES (?, ?)", w.Slug, w.Name)
+	if err != nil {
+		tx.Rollback()
+		if isDuplicate(err) {
+			return 0, repository.ErrConflict
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO workspaceMember(workspaceId, userId, role) VALUES (?, ?, ?)", id, owner, workspace.RoleAdmin)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// GetWorkspaces returns the workspaces the authenticated user is a member of
+func (r *Repository) GetWorkspaces(ctx context.Context) ([]workspace.Workspace, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.getWorkspaces(ctx, workspaceSelect+" WHERE m.userId IS NOT NULL ORDER BY w.id", owner)
+}
+
+// GetWorkspace returns the workspace with the provided id and nil if it doesn't exist
+// The role of the authenticated user is empty if it is not a member
+func (r *Repository) GetWorkspace(ctx context.Context, id int) (*workspace.Workspace, error) {
+	return r.getWorkspace(ctx, "w.id=?", id)
+}
+
+// GetWorkspaceBySlug returns the workspace with the provided slug and nil if it doesn't exist
+// The role of the authenticated user is empty if it is not a member
+func (r *Repository) GetWorkspaceBySlug(ctx context.Context, slug string) (*workspace.Workspace, error) {
+	return r.getWorkspace(ctx, "w.slug=?", slug)
+}
+
+// AddWorkspaceMember adds the user to the workspace of the request
+// Returns repository.ErrConflict if the user is already a member
+func (r *Repository) AddWorkspaceMember(ctx context.Context, userID int, role workspace.Role) error {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, "INSERT INTO workspaceMember(workspaceId, userId, role) VALUES (?, ?, ?)", ws, userID, role)
+	if isDuplicate(err) {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+// IsWorkspaceMember reports whether the user is a member of the workspace of the request
+func (r *Repository) IsWorkspaceMember(ctx context.Context, userID int) (bool, error) {
+	ws, err := workspaceID(ctx)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	err = r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM workspaceMember WHERE workspaceId=? AND userId=?)", ws, userID).Scan(&exists)
+	return exists, err
+}
+
+func (r *Repository) getWorkspace(ctx context.Context, cond string, value interface{}) (*workspace.Workspace, error) {
+	owner, err := ownerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workspaces, err := r.getWorkspaces(ctx, workspaceSelect+" WHERE "+cond+" LIMIT 1", owner, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) == 0 {
+		return nil, nil
+	}
+	return &workspaces[0], nil
+}
+
+func (r *Repository) getWorkspaces(ctx context.Context, query string, args ...interface{}) ([]workspace.Workspace, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []workspace.Workspace{}
+	for rows.Next() {
+		w := workspace.Workspace{}
+		if err := rows.Scan(&w.ID, &w.Slug, &w.Name, &w.Role); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, w)
+	}
+
+	return workspaces, rows.Err()
+}
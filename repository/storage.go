@@ -2,13 +2,181 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/aflog/todolist/audit"
 	"github.com/aflog/todolist/item"
+	"github.com/aflog/todolist/label"
+	"github.com/aflog/todolist/list"
+	"github.com/aflog/todolist/mention"
+	"github.com/aflog/todolist/share"
+	"github.com/aflog/todolist/user"
+	"github.com/aflog/todolist/workspace"
 )
 
-//Repository defines an interface for items storage
+// ErrConflict is returned when the stored data would violate a unique constraint
+var ErrConflict = errors.New("repository: conflict with existing data")
+
+// ErrNoParent is returned when a reply refers to a comment which doesn't exist on the same item
+var ErrNoParent = errors.New("repository: the parent comment doesn't exist")
+
+// ErrChecklistFull is returned when an entry is added to a checklist which already has item.MaxChecklist entries
+var ErrChecklistFull = errors.New("repository: the checklist is full")
+
+// ErrInvalidOrder is returned when a new order does not list every entry exactly once
+var ErrInvalidOrder = errors.New("repository: the order must list every entry exactly once")
+
+// ErrCycle is returned when a dependency would make an item depend on itself
+var ErrCycle = errors.New("repository: the dependency would create a cycle")
+
+// ErrUnknownState is returned when an item is put in a state which is not part of the workflow of its list
+var ErrUnknownState = errors.New("repository: the state is not part of the workflow of the list")
+
+// ErrTransition is returned when the workflow of the list does not allow moving an item to the state
+var ErrTransition = errors.New("repository: the workflow does not allow the transition")
+
+// ErrStateInUse is returned when a workflow change removes a state which still holds items
+var ErrStateInUse = errors.New("repository: the state still holds items")
+
+//Repository defines an interface for the to do list storage
+//Items and lists are scoped to the user carried by the context, a user sees its own items and lists
+//and the ones shared with it
+//All the data except the users is scoped to the workspace carried by the context
 type Repository interface {
+	ItemRepository
+	ListRepository
+	UserRepository
+	WorkspaceRepository
+	ShareRepository
+	AuditRepository
+	LabelRepository
+	MentionRepository
+	TimeRepository
+}
+
+//ItemRepository defines an interface for items storage
+type ItemRepository interface {
 	CreateItem(ctx context.Context, i item.Item) (int, error)
-	GetItems(ctx context.Context) ([]item.Item, error)
+	GetItems(ctx context.Context, q item.Query) ([]item.Item, error)
 	GetItem(ctx context.Context, id int) (*item.Item, error)
+	MoveItem(ctx context.Context, id int, listID int) error
+	PositionItem(ctx context.Context, id int, otherID int, after bool) (bool, error)
+	UpdateItem(ctx context.Context, i item.Item) (bool, error)
+	DeleteItem(ctx context.Context, id int) (bool, error)
+	GetComments(ctx context.Context, itemID int) ([]item.Comment, error)
+	GetComment(ctx context.Context, itemID int, id int) (*item.Comment, error)
+	GetCommentEdits(ctx context.Context, id int) ([]item.CommentEdit, error)
+	AddComment(ctx context.Context, itemID int, c item.Comment) (int, error)
+	UpdateComment(ctx context.Context, itemID int, c item.Comment) (bool, error)
+	DeleteComment(ctx context.Context, itemID int, id int) (bool, error)
+	AddChecklistEntry(ctx context.Context, itemID int, c item.ChecklistEntry) (int, error)
+	ToggleChecklistEntry(ctx context.Context, itemID int, id int) (*item.ChecklistEntry, error)
+	ReorderChecklist(ctx context.Context, itemID int, ids []int) error
+	DeleteChecklistEntry(ctx context.Context, itemID int, id int) (bool, error)
+	CreateAttachment(ctx context.Context, a item.Attachment) (int, error)
+	GetAttachments(ctx context.Context, itemID int) ([]item.Attachment, error)
+	GetAttachment(ctx context.Context, itemID int, id int) (*item.Attachment, error)
+	DeleteAttachment(ctx context.Context, itemID int, id int) (bool, error)
+	GetDependencies(ctx context.Context, itemID int) (*item.Dependencies, error)
+	AddDependency(ctx context.Context, itemID int, blockerID int) (bool, error)
+	RemoveDependency(ctx context.Context, itemID int, blockerID int) (bool, error)
+	GetItemVersions(ctx context.Context, itemID int) ([]item.Version, error)
+	GetItemVersion(ctx context.Context, itemID int, n int) (*item.Version, error)
+	RestoreItemVersion(ctx context.Context, itemID int, n int) (bool, error)
+	GetTrash(ctx context.Context) ([]item.Item, error)
+	GetDeletedItem(ctx context.Context, id int) (*item.Item, error)
+	RestoreItem(ctx context.Context, id int) (bool, error)
+	PurgeDeletedItems(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ArchiveItems(ctx context.Context, ids []int) (int, error)
+	UnarchiveItems(ctx context.Context, ids []int) (int, error)
+	ArchiveCompletedItems(ctx context.Context, completedBefore time.Time) (int64, error)
+	SnoozeItem(ctx context.Context, id int, until *time.Time) (bool, error)
+	ResurfaceItems(ctx context.Context, now time.Time) ([]item.Item, error)
+}
+
+//ListRepository defines an interface for lists storage
+type ListRepository interface {
+	CreateList(ctx context.Context, l list.List) (int, error)
+	GetLists(ctx context.Context) ([]list.List, error)
+	GetList(ctx context.Context, id int) (*list.List, error)
+	UpdateList(ctx context.Context, l list.List) error
+	DeleteList(ctx context.Context, id int) error
+	GetListItems(ctx context.Context, listID int, q item.Query) ([]item.Item, error)
+	GetWorkflow(ctx context.Context, listID int) (*list.Workflow, error)
+	UpdateWorkflow(ctx context.Context, listID int, w list.Workflow) ([]int, error)
+	GetMembers(ctx context.Context, listID int) ([]list.Member, error)
+	AddMember(ctx context.Context, listID int, m list.Member) error
+	UpdateMember(ctx context.Context, listID int, m list.Member) (bool, error)
+	RemoveMember(ctx context.Context, listID int, userID int) (bool, error)
+}
+
+//UserRepository defines an interface for users storage
+type UserRepository interface {
+	CreateUser(ctx context.Context, u user.User) (int, error)
+	GetUser(ctx context.Context, id int) (*user.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*user.User, error)
+	CreateAPIKey(ctx context.Context, k user.APIKey) (int, error)
+	GetAPIKeys(ctx context.Context) ([]user.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*user.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int) (bool, error)
+	TouchAPIKey(ctx context.Context, id int, usedAt time.Time) error
+}
+
+//WorkspaceRepository defines an interface for workspaces storage
+type WorkspaceRepository interface {
+	CreateWorkspace(ctx context.Context, w workspace.Workspace) (int, error)
+	GetWorkspaces(ctx context.Context) ([]workspace.Workspace, error)
+	GetWorkspace(ctx context.Context, id int) (*workspace.Workspace, error)
+	GetWorkspaceBySlug(ctx context.Context, slug string) (*workspace.Workspace, error)
+	AddWorkspaceMember(ctx context.Context, userID int, role workspace.Role) error
+	IsWorkspaceMember(ctx context.Context, userID int) (bool, error)
+}
+
+//ShareRepository defines an interface for public share links storage
+//The links are resolved by their token hash without a user in the context
+type ShareRepository interface {
+	CreateShare(ctx context.Context, s share.Share) (int, error)
+	GetShares(ctx context.Context) ([]share.Share, error)
+	RevokeShare(ctx context.Context, id int) (bool, error)
+	GetShareByHash(ctx context.Context, hash string) (*share.Share, error)
+	CountShareAccess(ctx context.Context, id int) error
+	GetSharedItem(ctx context.Context, s share.Share) (*item.Item, error)
+	GetSharedList(ctx context.Context, s share.Share) (*list.List, []item.Item, error)
+}
+
+//AuditRepository defines an interface for reading the audit log
+//The entries are written by the mutations of the other repositories, they are never updated nor deleted
+type AuditRepository interface {
+	GetItemHistory(ctx context.Context, itemID int, before int) ([]audit.Entry, error)
+	GetAuditEntries(ctx context.Context, f audit.Filter) ([]audit.Entry, error)
+}
+
+//LabelRepository defines an interface for the labels shared by the items of a workspace
+type LabelRepository interface {
+	GetLabels(ctx context.Context) ([]label.Label, error)
+	GetLabel(ctx context.Context, id int) (*label.Label, error)
+	CreateLabel(ctx context.Context, l label.Label) (int, error)
+	UpdateLabel(ctx context.Context, l label.Label) error
+	DeleteLabel(ctx context.Context, id int) error
+	MergeLabels(ctx context.Context, sourceID int, targetID int) (bool, error)
+	GetLabelTree(ctx context.Context) ([]*label.Node, error)
+	SuggestLabels(ctx context.Context, prefix string, limit int) ([]label.Label, error)
+}
+
+//MentionRepository defines an interface for the mentions of the users in comments and item descriptions
+type MentionRepository interface {
+	AddMentions(ctx context.Context, itemID int, commentID int, usernames []string) ([]mention.Mention, error)
+	GetMentions(ctx context.Context, unread bool, limit int) ([]mention.Mention, error)
+	ReadMentions(ctx context.Context, ids []int) (int, error)
+}
+
+//TimeRepository defines an interface for the time tracked by the users on the items
+type TimeRepository interface {
+	GetTimeEntries(ctx context.Context, itemID int) ([]item.TimeEntry, error)
+	AddTimeEntry(ctx context.Context, e item.TimeEntry) (int, error)
+	StartTimer(ctx context.Context, itemID int) (*item.TimeEntry, error)
+	StopTimer(ctx context.Context, itemID int) (*item.TimeEntry, error)
+	DeleteTimeEntry(ctx context.Context, itemID int, id int) (bool, error)
+	GetTimeReport(ctx context.Context, from time.Time, to time.Time) ([]item.TimeSummary, error)
 }
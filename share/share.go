@@ -0,0 +1,70 @@
+package share
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// DefaultTTL is the validity of a share link created without an expiration time
+const DefaultTTL = 7 * 24 * time.Hour
+
+// MaxTTL is the longest validity of a share link
+const MaxTTL = 90 * 24 * time.Hour
+
+// Share defines the structure of a public read-only link to one item or one list
+// Exactly one of ItemID and ListID is set. Only the hash of the token is stored,
+// the token itself is returned once when the link is created
+type Share struct {
+	ID             int        `json:"id"`
+	WorkspaceID    int        `json:"-"`
+	CreatedBy      int        `json:"-"`
+	ItemID         int        `json:"itemId,omitempty"`
+	ListID         int        `json:"listId,omitempty"`
+	Hash           string     `json:"-"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	RevokedAt      *time.Time `json:"revokedAt"`
+	AccessCount    int        `json:"accessCount"`
+	LastAccessedAt *time.Time `json:"lastAccessedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// Validate that the expiration time is in the future and not later than MaxTTL
+// A zero expiration time is replaced with DefaultTTL
+func (s *Share) Validate() error {
+	now := time.Now()
+	if s.ExpiresAt.IsZero() {
+		s.ExpiresAt = now.Add(DefaultTTL)
+	}
+	if !s.ExpiresAt.After(now) {
+		return errors.New("share: expiresAt must be in the future")
+	}
+	if s.ExpiresAt.After(now.Add(MaxTTL)) {
+		return errors.New("share: expiresAt can not be more than 90 days ahead")
+	}
+	return nil
+}
+
+// Active reports whether the link still grants access
+func (s *Share) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// NewToken returns a new unguessable token and the hash to store
+func NewToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the hex encoded sha256 of the token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+// Scopes which can be granted to an API key
+const (
+	ScopeItemsRead  = "items:read"
+	ScopeItemsWrite = "items:write"
+)
+
+// APIKey defines the structure of a long-lived key used by scripts to call the api on behalf of a user
+// Only the hash of the key is stored, the key itself is returned once when it is created
+// A key can only access the workspace it was created in
+type APIKey struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	WorkspaceID int        `json:"workspaceId"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Hash        string     `json:"-"`
+	Scopes      []string   `json:"scopes"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// Validate that all required field are present and the scopes are known
+func (k *APIKey) Validate() error {
+	if k.Name == "" {
+		return errors.New("apikey: name field is required and can not be empty")
+	}
+	if len(k.Scopes) == 0 {
+		return errors.New("apikey: at least one scope is required")
+	}
+	for _, s := range k.Scopes {
+		if s != ScopeItemsRead && s != ScopeItemsWrite {
+			return errors.New("apikey: unknown scope " + s)
+		}
+	}
+	return nil
+}
+
+// Revoked reports whether the key can no longer be used
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,30}$`)
+
+// minPasswordLength is the minimal number of characters of a password
+const minPasswordLength = 8
+
+// User defines the structure of an account calling the api
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	UpdatedAt    time.Time `json:"-"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+// Validate that all required field are present
+func (u *User) Validate() error {
+	if !usernamePattern.MatchString(u.Username) {
+		return errors.New("user: username must have 3 to 30 letters, digits or _.- characters")
+	}
+	if u.Email == "" {
+		return errors.New("user: email field is required and can not be empty")
+	}
+	return nil
+}
+
+// SetPassword stores the bcrypt hash of the password, the password itself is never kept
+func (u *User) SetPassword(password string) error {
+	if len(password) < minPasswordLength {
+		return errors.New("user: password must have at least 8 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether the password matches the stored hash
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated user
+func NewContext(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// FromContext returns the authenticated user carried by ctx
+func FromContext(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(contextKey{}).(*User)
+	return u, ok && u != nil
+}
@@ -0,0 +1,57 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// DefaultSlug identifies the workspace used when the request does not select one
+// Every registered user is a member of it
+const DefaultSlug = "default"
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,30}$`)
+
+// Role defines what a member is allowed to do with the workspace
+type Role string
+
+// Roles of the workspace members
+const (
+	RoleMember Role = "member"
+	RoleAdmin  Role = "admin"
+)
+
+// Workspace defines the structure of a tenant, the data of one workspace is never visible from another
+// Role is the role of the requesting user, it is not stored with the workspace
+type Workspace struct {
+	ID        int       `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role,omitempty"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// Validate that all required field are present and the slug can be used as a subdomain
+func (w *Workspace) Validate() error {
+	if !slugPattern.MatchString(w.Slug) {
+		return errors.New("workspace: slug must have 2 to 31 lowercase letters, digits or dashes")
+	}
+	if w.Name == "" {
+		return errors.New("workspace: name field is required and can not be empty")
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the workspace of the request
+func NewContext(ctx context.Context, w *Workspace) context.Context {
+	return context.WithValue(ctx, contextKey{}, w)
+}
+
+// FromContext returns the workspace carried by ctx
+func FromContext(ctx context.Context) (*Workspace, bool) {
+	w, ok := ctx.Value(contextKey{}).(*Workspace)
+	return w, ok && w != nil
+}